@@ -2,15 +2,19 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/mfmayer/gosk"
 	"github.com/mfmayer/gosk/pkg/gpt"
 	"github.com/mfmayer/gosk/pkg/llm"
 	"github.com/mfmayer/gosk/pkg/skills/chat"
+	"github.com/mfmayer/gosk/pkg/store"
 )
 
 func waitForInput() string {
@@ -20,37 +24,88 @@ func waitForInput() string {
 	return text
 }
 
-func printOutput(output string) {
-	fmt.Printf("Bot: %s", output)
+// printStream prints the streamed response deltas as soon as they arrive
+func printStream(deltas <-chan llm.Content) {
+	fmt.Print("Bot: ")
+	for delta := range deltas {
+		fmt.Print(delta.String())
+	}
+	fmt.Println()
 }
 
 func main() {
+	sessionID := flag.String("session", "", "ID of the session to resume or start, the conversation isn't stored if empty")
+	sessionsDir := flag.String("sessions", "sessions", "directory the sessions are stored in")
+	list := flag.Bool("list", false, "list the stored sessions")
+	fork := flag.String("fork", "", "ID of a new session that continues a copy of the session's conversation")
+	summaryThreshold := flag.Int("summarize", 0, "number of tokens of the conversation that trigger the summary of its oldest part, no summary if 0")
+	flag.Parse()
+
+	conversations, err := store.NewFileStore(*sessionsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conversations.Close()
+	if *list {
+		sessionIDs, err := conversations.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, sessionID := range sessionIDs {
+			fmt.Println(sessionID)
+		}
+		return
+	}
+	if *fork != "" {
+		if err := conversations.Fork(*sessionID, *fork); err != nil {
+			log.Fatal(err)
+		}
+		*sessionID = *fork
+	}
+
+	// cancel running requests on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// create semantic kernel and add chat skill
 	kernel := gosk.NewKernel()
 	kernel.RegisterGenerators(gpt.Register)
-	kernel.RegisterSkills(chat.Register)
+	chatOptions := []chat.Option{chat.WithStore(conversations)}
+	if *summaryThreshold > 0 {
+		chatOptions = append(chatOptions, chat.WithSummary(*summaryThreshold, 4, nil))
+	}
+	kernel.RegisterSkills(chat.RegisterWith(chatOptions...))
 
 	chatFunction, err := kernel.FindFunction("chat", "chatgpt")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// start chat
+	// start chat, a stored session's conversation is resumed by the chat skill
 	inputString := waitForInput()
 	input := llm.NewContent(inputString).
 		SetRole(llm.RoleUser).With("date", time.Now().String()).
 		With("botName", "Ida").With("firstName", "John").With("language", "german")
+	if *sessionID != "" {
+		input.With(chat.SessionProperty, *sessionID)
+	}
 	// response, err := kernel.Call(input, chatFunction)
 	// if err != nil {
 	// 	log.Fatal(err)
 	// }
 	var response llm.Content
 	for {
-		response, err = kernel.Call(input, chatFunction)
+		deltas := make(chan llm.Content)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			response, err = kernel.CallStream(ctx, input, deltas, chatFunction)
+		}()
+		printStream(deltas)
+		<-done
 		if err != nil {
 			log.Fatal(err)
 		}
-		printOutput(response.String())
 		inputString := waitForInput()
 		input = llm.NewContent(inputString).
 			SetRole(llm.RoleUser).
@@ -0,0 +1,137 @@
+package gosk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"text/template"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// PromptFileExtension is the extension of single-file function definitions with YAML front matter and prompt
+const PromptFileExtension = ".prompt"
+
+// configExtensions are the supported extensions of config files in the order of their precedence
+var configExtensions = []string{".json", ".yaml", ".yml"}
+
+// readConfig reads the config file with given base name in dir of fsys (e.g. "config" for `config.json`, `config.yaml`
+// or `config.yml`) and returns its content as JSON. The returned error wraps fs.ErrNotExist if no config file exists.
+func readConfig(fsys fs.FS, dir string, baseName string) (data []byte, configFile string, err error) {
+	for _, extension := range configExtensions {
+		configFile = path.Join(dir, baseName+extension)
+		data, err = fs.ReadFile(fsys, configFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			err = fmt.Errorf("reading `%s` failed: %w", configFile, err)
+			return
+		}
+		if extension != ".json" {
+			if data, err = yamlToJSON(data); err != nil {
+				err = fmt.Errorf("unmarshalling `%s` failed: %w", configFile, err)
+			}
+		}
+		return
+	}
+	configFile = path.Join(dir, baseName+".json")
+	err = fmt.Errorf("opening `%s` failed: %w", configFile, fs.ErrNotExist)
+	return
+}
+
+// yamlToJSON converts YAML to JSON, so that YAML configs are unmarshalled like JSON configs
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// splitFrontMatter splits a single-file definition into its YAML front matter (between two `---` lines) and its body
+func splitFrontMatter(data []byte) (frontMatter []byte, body []byte, err error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, nil, errors.New("missing front matter")
+	}
+	data = data[len("---\n"):]
+	end := bytes.Index(data, []byte("\n---\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(data, []byte("\n---")) {
+			return nil, nil, errors.New("front matter isn't closed")
+		}
+		return data[:len(data)-len("\n---")], nil, nil
+	}
+	return data[:end], data[end+len("\n---\n"):], nil
+}
+
+// ParseSemanticFunctionFile parses a single-file function definition, i.e. YAML front matter with the function's config
+// followed by its prompt template:
+//
+//	---
+//	name: joke
+//	description: Tell a joke
+//	generator: default
+//	---
+//	Tell a joke about {{.}}.
+//
+// A system message template can be set in the front matter with `system`.
+func ParseSemanticFunctionFile(fsys fs.FS, file string, generators map[string]llm.Generator, options ...parseSemanticFunctionFromFSOption) (function *Function, err error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		err = fmt.Errorf("reading `%s` failed: %w", file, err)
+		return
+	}
+	frontMatter, body, err := splitFrontMatter(data)
+	if err != nil {
+		err = fmt.Errorf("parsing `%s` failed: %w", file, err)
+		return
+	}
+	var config map[string]interface{}
+	if err = yaml.Unmarshal(frontMatter, &config); err != nil {
+		err = fmt.Errorf("unmarshalling `%s` failed: %w", file, err)
+		return
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	config["prompt"] = string(body)
+	return parseInlineSemanticFunction(fsys, file, config, generators, options...)
+}
+
+// parseInlineSemanticFunction parses a function whose config contains its `prompt` and optional `system` template
+func parseInlineSemanticFunction(fsys fs.FS, source string, config map[string]interface{}, generators map[string]llm.Generator, options ...parseSemanticFunctionFromFSOption) (function *Function, err error) {
+	prompt, ok := config["prompt"].(string)
+	if !ok {
+		err = fmt.Errorf("missing prompt in `%s`", source)
+		return
+	}
+	system, _ := config["system"].(string)
+	functionConfig := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if key != "prompt" && key != "system" {
+			functionConfig[key] = value
+		}
+	}
+	data, err := json.Marshal(functionConfig)
+	if err != nil {
+		err = fmt.Errorf("marshalling `%s` failed: %w", source, err)
+		return
+	}
+	promptTemplate := func() (*template.Template, error) {
+		promptTemplate, err := template.New(llm.PromptTemplateName).Funcs(llm.TemplateFuncs()).Parse(prompt)
+		if err == nil && system != "" {
+			_, err = promptTemplate.New(SystemTemplateName).Parse(system)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return promptTemplate, nil
+	}
+	return parseSemanticFunction(fsys, data, source, promptTemplate, "", generators, options...)
+}
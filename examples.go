@@ -0,0 +1,198 @@
+package gosk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// ExamplesFileName is the name of a semantic function's default file with few-shot examples
+const ExamplesFileName = "examples.jsonl"
+
+// ExampleStrategy defines how examples are selected from a function's examples
+type ExampleStrategy string
+
+const (
+	// ExampleStrategyAll selects all examples
+	ExampleStrategyAll ExampleStrategy = "all"
+	// ExampleStrategyFirst selects the first examples
+	ExampleStrategyFirst ExampleStrategy = "first"
+	// ExampleStrategyRandom selects random examples, always the same ones if a seed is configured
+	ExampleStrategyRandom ExampleStrategy = "random"
+	// ExampleStrategySimilar selects the examples whose inputs are most similar to the input by their embeddings
+	ExampleStrategySimilar ExampleStrategy = "similar"
+)
+
+// Example is a few-shot example of a function's input and the expected output. Examples are sent as user and assistant
+// messages before the actual prompt.
+type Example struct {
+	Input  interface{} `json:"input"`
+	Output interface{} `json:"output"`
+}
+
+// ExamplesConfig configures a function's few-shot examples and how many and which of them are sent with each call
+type ExamplesConfig struct {
+	// File with examples as JSON lines (see ParseExamples)
+	File string `json:"file"`
+	// Strategy to select examples, all examples are selected by default
+	Strategy ExampleStrategy `json:"strategy,omitempty"`
+	// Count of examples to select, all examples if not set
+	Count int `json:"count,omitempty"`
+	// Seed of the random strategy to select the same examples each time (incl. seed 0), other examples are selected for
+	// each call if not set
+	Seed *int64 `json:"seed,omitempty"`
+	// Embedder is the skill's generator that creates the embeddings for the similar strategy, the function's generator if not set
+	Embedder string `json:"embedder,omitempty"`
+}
+
+// ParseExamples parses examples from JSON lines, e.g. {"input": "flowers", "output": "Why did the tulip blush? ..."}
+func ParseExamples(r io.Reader) (examples []*Example, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) <= 0 {
+			continue
+		}
+		example := &Example{}
+		if err = json.Unmarshal(data, example); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		examples = append(examples, example)
+	}
+	err = scanner.Err()
+	return
+}
+
+// exampleSelector selects a function's examples for an input with the configured strategy
+type exampleSelector struct {
+	examples []*Example
+	config   ExamplesConfig
+	embedder llm.Embedder
+	// mutex guards embeddings, that are created on first use
+	mutex      sync.Mutex
+	embeddings [][]float64
+}
+
+// newExampleSelector parses the configured examples file and checks the configured strategy
+func newExampleSelector(fsys fs.FS, config *ExamplesConfig, generators map[string]llm.Generator, generatorName string) (selector *exampleSelector, err error) {
+	selector = &exampleSelector{config: *config}
+	file, err := fsys.Open(config.File)
+	if err != nil {
+		return nil, fmt.Errorf("opening examples `%s` failed: %w", config.File, err)
+	}
+	defer file.Close()
+	if selector.examples, err = ParseExamples(file); err != nil {
+		return nil, fmt.Errorf("parsing examples `%s` failed: %w", config.File, err)
+	}
+	switch config.Strategy {
+	case "", ExampleStrategyAll, ExampleStrategyFirst, ExampleStrategyRandom:
+	case ExampleStrategySimilar:
+		if config.Embedder != "" {
+			generatorName = config.Embedder
+		}
+		embedder, ok := generators[generatorName].(llm.Embedder)
+		if !ok {
+			return nil, fmt.Errorf("generator `%s` can't create embeddings for examples `%s`", generatorName, config.File)
+		}
+		selector.embedder = embedder
+	default:
+		return nil, fmt.Errorf("unknown strategy `%s` for examples `%s`", config.Strategy, config.File)
+	}
+	return
+}
+
+// selectExamples selects the examples for given input
+func (s *exampleSelector) selectExamples(ctx context.Context, input llm.Content) ([]*Example, error) {
+	count := s.config.Count
+	if count <= 0 || count > len(s.examples) {
+		count = len(s.examples)
+	}
+	switch s.config.Strategy {
+	case ExampleStrategyFirst:
+		return s.examples[:count], nil
+	case ExampleStrategyRandom:
+		seed := time.Now().UnixNano()
+		if s.config.Seed != nil {
+			seed = *s.config.Seed
+		}
+		indices := rand.New(rand.NewSource(seed)).Perm(len(s.examples))[:count]
+		// keep the examples' order
+		sort.Ints(indices)
+		examples := make([]*Example, 0, count)
+		for _, i := range indices {
+			examples = append(examples, s.examples[i])
+		}
+		return examples, nil
+	case ExampleStrategySimilar:
+		return s.similarExamples(ctx, input, count)
+	}
+	return s.examples, nil
+}
+
+// similarExamples selects the examples whose inputs are most similar to the input, the most similar example is the last one
+func (s *exampleSelector) similarExamples(ctx context.Context, input llm.Content, count int) ([]*Example, error) {
+	embeddings, err := s.exampleEmbeddings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inputEmbeddings, err := s.embedder.Embed(ctx, []string{input.String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(inputEmbeddings) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(inputEmbeddings))
+	}
+	similarities := make([]float64, len(s.examples))
+	indices := make([]int, len(s.examples))
+	for i := range s.examples {
+		similarities[i] = llm.CosineSimilarity(embeddings[i], inputEmbeddings[0])
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return similarities[indices[i]] > similarities[indices[j]]
+	})
+	examples := make([]*Example, count)
+	for i, index := range indices[:count] {
+		examples[count-1-i] = s.examples[index]
+	}
+	return examples, nil
+}
+
+// exampleEmbeddings returns the embeddings of the examples' inputs and creates them on first use. The embeddings are
+// created without holding the mutex, concurrent first calls may create them more than once.
+func (s *exampleSelector) exampleEmbeddings(ctx context.Context) ([][]float64, error) {
+	s.mutex.Lock()
+	embeddings := s.embeddings
+	s.mutex.Unlock()
+	if embeddings != nil {
+		return embeddings, nil
+	}
+	texts := make([]string, 0, len(s.examples))
+	for _, example := range s.examples {
+		texts = append(texts, llm.NewContent(example.Input).String())
+	}
+	embeddings, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(s.examples) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(s.examples), len(embeddings))
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.embeddings == nil {
+		s.embeddings = embeddings
+	}
+	return s.embeddings, nil
+}
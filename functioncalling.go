@@ -0,0 +1,123 @@
+package gosk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// FunctionNameSeparator separates skill and function name in function definitions' names,
+// since the model's function names must not contain dots
+const FunctionNameSeparator = "-"
+
+// InputPropertyName is the name of the default input property (with empty name) in function definitions and arguments
+const InputPropertyName = "input"
+
+// Definition returns the function's definition with given name and its input properties as JSON schema
+func (f *Function) Definition(name string) llm.FunctionDefinition {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for parameterName, parameter := range f.InputProperties {
+		propertyName := f.definitionPropertyName(parameterName)
+		properties[propertyName] = parameter.schema()
+		if parameter.Required {
+			required = append(required, propertyName)
+		}
+	}
+	sort.Strings(required)
+	parameters := map[string]interface{}{
+		"type":       TypeObject,
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		parameters["required"] = required
+	}
+	return llm.FunctionDefinition{
+		Name:        name,
+		Description: f.Description,
+		Parameters:  parameters,
+	}
+}
+
+// definitionPropertyName returns the definition's property name of given parameter name
+func (f *Function) definitionPropertyName(parameterName string) string {
+	if parameterName == "" {
+		return InputPropertyName
+	}
+	return parameterName
+}
+
+// InputFromArguments creates the function's input from arguments that follow the function's definition.
+// The "input" argument is set as the input's value (and as "input" property if the function defines it).
+func (f *Function) InputFromArguments(arguments map[string]interface{}) llm.Content {
+	input := llm.NewContent()
+	_, hasInputProperty := f.InputProperties[InputPropertyName]
+	for name, value := range arguments {
+		if name == InputPropertyName {
+			input.Set(value)
+			if !hasInputProperty {
+				continue
+			}
+		}
+		input.With(name, value)
+	}
+	return input
+}
+
+// schema returns the parameter's JSON schema
+func (p *Parameter) schema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	if p.Type != "" {
+		schema["type"] = p.Type
+	}
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	if p.Default != nil {
+		schema["default"] = p.Default
+	}
+	return schema
+}
+
+// ContextWithFunctions returns a context that advertises the functions with given paths (`skillName.functionName`)
+// to generators that support function calling. Their names in the definitions are `skillName-functionName`.
+// If execute is true, function calls requested by the model are executed by the kernel and their responses
+// are passed back to the model until it produces a final answer. Otherwise the function call is returned as response.
+func (sk *SemanticKernel) ContextWithFunctions(ctx context.Context, execute bool, functionPaths ...string) (context.Context, error) {
+	functions, err := sk.FindFunctions(functionPaths...)
+	if err != nil {
+		return ctx, err
+	}
+	definitions := make([]llm.FunctionDefinition, 0, len(functions))
+	functionsByName := make(map[string]*Function, len(functions))
+	for i, function := range functions {
+		name := strings.ReplaceAll(functionPaths[i], ".", FunctionNameSeparator)
+		definitions = append(definitions, function.Definition(name))
+		functionsByName[name] = function
+	}
+	if !execute {
+		return llm.ContextWithFunctions(ctx, definitions, nil), nil
+	}
+	executor := func(ctx context.Context, call llm.Content) (response llm.Content, err error) {
+		function, ok := functionsByName[call.Name()]
+		if !ok {
+			err = fmt.Errorf("%w: `%s`", ErrFunctionNotFound, call.Name())
+			return
+		}
+		arguments, ok := call.Value().(map[string]interface{})
+		if !ok && call.Value() != nil {
+			err = fmt.Errorf("invalid function call arguments: %s", call.String())
+			return
+		}
+		// called functions must not advertise functions themselves
+		ctx = llm.ContextWithFunctions(ctx, nil, nil)
+		return sk.CallContext(ctx, function.InputFromArguments(arguments), function)
+	}
+	return llm.ContextWithFunctions(ctx, definitions, executor), nil
+}
@@ -5,6 +5,10 @@ go 1.20
 require (
 	github.com/joho/godotenv v1.5.1
 	github.com/mfmayer/gopenai v0.1.0
+	go.etcd.io/bbolt v1.3.8
+	gopkg.in/yaml.v3 v3.0.1
 )
 
+require golang.org/x/sys v0.10.0 // indirect
+
 // replace github.com/mfmayer/gopenai => ../gopenai
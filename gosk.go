@@ -1,9 +1,16 @@
 package gosk
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/mfmayer/gosk/pkg/llm"
 )
@@ -12,37 +19,68 @@ var (
 	// ErrMissingParameter is returned when a required parameter is missing
 	ErrGeneratorAlreadyRegistered = errors.New("generator already registered")
 	ErrMissingParameter           = errors.New("missing parameter")
+	ErrGeneratorNotFound          = errors.New("generator not found")
 	ErrSkillNotFound              = errors.New("skill not found")
 	ErrFunctionNotFound           = errors.New("function not found")
 )
 
-// SemanticKernel
+// SemanticKernel is safe for concurrent use. Its skills and generators can be (un)registered and replaced while functions are called.
 type SemanticKernel struct {
+	// mutex guards registeredGenerators, skills, partials and closed
+	mutex                sync.RWMutex
 	registeredGenerators llm.NewGeneratorFuncMap
 	skills               map[string]*Skill
+	partials             []*template.Template
+	options              newKernelOptions
+	stopWatching         chan struct{}
+	watching             sync.WaitGroup
+	closed               bool
 }
 
 type newKernelOption func(*newKernelOptions)
 
 type newKernelOptions struct {
+	hotReloadInterval time.Duration
+	logger            *log.Logger
+}
+
+// WithHotReload enables hot reloading of skills that are loaded with LoadSkillsFromDir. Their directories are checked
+// for changed `config.json` and `*.tmpl` files with given interval and changed skills are parsed again and replaced.
+func WithHotReload(interval time.Duration) newKernelOption {
+	return func(options *newKernelOptions) {
+		options.hotReloadInterval = interval
+	}
+}
+
+// WithLogger sets the logger that is used by the kernel (e.g. to log hot reloads), default is the standard logger
+func WithLogger(logger *log.Logger) newKernelOption {
+	return func(options *newKernelOptions) {
+		options.logger = logger
+	}
 }
 
 // NewKernel creates new kernel and tries to retrieve the OpenAI key from "OPENAI_API_KEY" environment variable or .env file in current working directory
 func NewKernel(opts ...newKernelOption) *SemanticKernel {
-	options := &newKernelOptions{}
+	options := newKernelOptions{
+		logger: log.Default(),
+	}
 	for _, opt := range opts {
-		opt(options)
+		opt(&options)
 	}
 
 	kernel := &SemanticKernel{
 		registeredGenerators: llm.NewGeneratorFuncMap{},
 		skills:               map[string]*Skill{},
+		options:              options,
+		stopWatching:         make(chan struct{}),
 	}
 	return kernel
 }
 
 // RegisterGenerators registers new generators with their registaration functions and make them available to skills
 func (sk *SemanticKernel) RegisterGenerators(registrationFuncs ...llm.RegistrationFunc) (err error) {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
 	for _, factory := range registrationFuncs {
 		typeID, newGeneratorFunc := factory()
 		if _, exists := sk.registeredGenerators[typeID]; exists {
@@ -53,10 +91,33 @@ func (sk *SemanticKernel) RegisterGenerators(registrationFuncs ...llm.Registrati
 	return
 }
 
+// UnregisterGenerator unregisters the generator with given type ID, so that it isn't available to skills that are registered
+// or loaded afterwards. Skills that already use generators of this type keep them.
+func (sk *SemanticKernel) UnregisterGenerator(typeID string) error {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if _, exists := sk.registeredGenerators[typeID]; !exists {
+		return fmt.Errorf("%w: %s", ErrGeneratorNotFound, typeID)
+	}
+	delete(sk.registeredGenerators, typeID)
+	return nil
+}
+
+// generatorFactories returns a snapshot of the registered generators
+func (sk *SemanticKernel) generatorFactories() llm.NewGeneratorFuncMap {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	generatorFactories := make(llm.NewGeneratorFuncMap, len(sk.registeredGenerators))
+	for typeID, newGeneratorFunc := range sk.registeredGenerators {
+		generatorFactories[typeID] = newGeneratorFunc
+	}
+	return generatorFactories
+}
+
 // RegisterSkills registers new skills with their registration functions and adds them to the kernel with their individual names
 func (sk *SemanticKernel) RegisterSkills(registrationFuncs ...SkillRegistrationFunc) (err error) {
 	for _, registrationFunc := range registrationFuncs {
-		skill, registrationErr := registrationFunc(sk.registeredGenerators)
+		skill, registrationErr := registrationFunc(sk.generatorFactories())
 		if registrationErr != nil {
 			err = errors.Join(err, fmt.Errorf("error registering %s: %w", skill, registrationErr))
 			continue
@@ -69,16 +130,92 @@ func (sk *SemanticKernel) RegisterSkills(registrationFuncs ...SkillRegistrationF
 // AddSkills adds already initialized skills to the kernel with their individual names
 func (sk *SemanticKernel) AddSkills(skills ...*Skill) (err error) {
 	for _, skill := range skills {
+		if skill == nil {
+			err = errors.Join(err, errors.New("skill is nil"))
+			continue
+		}
 		err = errors.Join(err, sk.addSkill(skill.Name, skill))
 	}
+	return
+}
+
+// ReplaceSkill replaces the kernel's skill with the same name or adds it if it doesn't exist yet.
+// Calls that are in flight finish with the replaced skill.
+func (sk *SemanticKernel) ReplaceSkill(skill *Skill) error {
+	if skill == nil {
+		return errors.New("skill is nil")
+	}
+	if skill.Name == "" {
+		return errors.New("skill has no name")
+	}
+	sanitizeSkill(skill.Name, skill)
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.skills[skill.Name] = skill
+	return nil
+}
+
+// RemoveSkill removes the skill with given name from the kernel. Calls that are in flight finish with the removed skill.
+func (sk *SemanticKernel) RemoveSkill(skillName string) error {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if _, exists := sk.skills[skillName]; !exists {
+		return fmt.Errorf("%w: %s", ErrSkillNotFound, skillName)
+	}
+	delete(sk.skills, skillName)
 	return nil
 }
 
+// LoadSkillsFromFS parses all skills found in the fsys file system (see ParseSemanticSkillsFromFS) with the kernel's
+// registered generators and adds them to the kernel. Skills that can't be parsed or added are reported in the returned
+// error without aborting the others.
+func (sk *SemanticKernel) LoadSkillsFromFS(fsys fs.FS) (err error) {
+	_, err = sk.loadSkills(fsys)
+	return
+}
+
+// loadSkills loads all skills found in fsys and returns watchers for all found skill directories
+func (sk *SemanticKernel) loadSkills(fsys fs.FS) (watchers []*skillWatcher, err error) {
+	skillDirs, err := findSkillDirs(fsys)
+	generatorFactories := sk.generatorFactories()
+	for _, dir := range skillDirs {
+		watcher := &skillWatcher{
+			fsys:        fsys,
+			dir:         dir,
+			fingerprint: skillFingerprint(fsys, dir),
+		}
+		watchers = append(watchers, watcher)
+		skill, parseErr := parseSemanticSkillInDir(fsys, dir, generatorFactories)
+		if parseErr != nil {
+			err = errors.Join(err, &SkillError{Path: dir, Err: parseErr})
+			continue
+		}
+		if addErr := sk.addSkill(skill.Name, skill); addErr != nil {
+			err = errors.Join(err, &SkillError{Path: dir, Err: addErr})
+			continue
+		}
+		watcher.name, watcher.skill = skill.Name, skill
+	}
+	return
+}
+
 func (sk *SemanticKernel) addSkill(name string, skill *Skill) error {
-	// sanitize skill and parameter names
 	if skill == nil {
 		return fmt.Errorf("skill `%s` is nil", name)
 	}
+	sanitizeSkill(name, skill)
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	// check if skill already exists
+	if _, ok := sk.skills[name]; ok {
+		return fmt.Errorf("skill `%s` already added", name)
+	}
+	sk.skills[name] = skill
+	return nil
+}
+
+// sanitizeSkill sanitizes skill, function and parameter names
+func sanitizeSkill(name string, skill *Skill) {
 	if skill.Name == "" {
 		skill.Name = name
 	}
@@ -92,16 +229,26 @@ func (sk *SemanticKernel) addSkill(name string, skill *Skill) error {
 			}
 		}
 	}
-	// check if skill already exists
-	if _, ok := sk.skills[name]; ok {
-		return fmt.Errorf("skill `%s` already added", name)
+}
+
+// Skills returns all skills of the kernel sorted by their names
+func (sk *SemanticKernel) Skills() (skills []*Skill) {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	skills = make([]*Skill, 0, len(sk.skills))
+	for _, skill := range sk.skills {
+		skills = append(skills, skill)
 	}
-	sk.skills[name] = skill
-	return nil
+	sort.Slice(skills, func(i, j int) bool {
+		return skills[i].Name < skills[j].Name
+	})
+	return
 }
 
 // FindSkill finds a skill by name and returns it or an error if not found
 func (sk *SemanticKernel) FindSkill(skillName string) (skill *Skill, err error) {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
 	if skill, ok := sk.skills[skillName]; ok {
 		return skill, nil
 	}
@@ -144,25 +291,55 @@ func (sk *SemanticKernel) FindFunctions(functionPaths ...string) (functions []*F
 
 // CallWithName as shortcut to SemanticKernel.FindFunction and SemanticKernel.Call
 func (sk *SemanticKernel) CallWithName(input llm.Content, skillName string, skillFunction string) (response llm.Content, err error) {
+	return sk.CallWithNameContext(context.Background(), input, skillName, skillFunction)
+}
+
+// CallWithNameContext as shortcut to SemanticKernel.FindFunction and SemanticKernel.CallContext
+func (sk *SemanticKernel) CallWithNameContext(ctx context.Context, input llm.Content, skillName string, skillFunction string) (response llm.Content, err error) {
 	function, err := sk.FindFunction(skillName, skillFunction)
 	if err != nil {
 		return
 	}
-	return sk.Call(input, function)
+	return sk.CallContext(ctx, input, function)
 }
 
 // Call one or more functions in a row.
 // The given input (incl. all its properties) is passed to each function after it has been
 // updated with the previous function's response value.
 func (sk *SemanticKernel) Call(input llm.Content, functions ...*Function) (response llm.Content, err error) {
+	return sk.CallContext(context.Background(), input, functions...)
+}
+
+// CallContext calls one or more functions in a row like Call. The given context is passed to each function
+// and the chain is aborted as soon as the context is cancelled or its deadline is exceeded.
+func (sk *SemanticKernel) CallContext(ctx context.Context, input llm.Content, functions ...*Function) (response llm.Content, err error) {
+	return sk.callChain(ctx, input, nil, functions...)
+}
+
+// CallStream calls one or more functions in a row like CallContext, but streams the response of the last
+// function into the deltas channel. Functions that don't generate their response with a streaming generator
+// send their complete response as single delta. The deltas channel is closed when CallStream returns.
+func (sk *SemanticKernel) CallStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content, functions ...*Function) (response llm.Content, err error) {
+	defer close(deltas)
+	return sk.callChain(ctx, input, deltas, functions...)
+}
+
+// callChain calls the functions in a row and streams the last function's response into deltas if not nil
+func (sk *SemanticKernel) callChain(ctx context.Context, input llm.Content, deltas chan<- llm.Content, functions ...*Function) (response llm.Content, err error) {
 	if len(functions) <= 0 {
 		err = errors.New("no functions to call")
 		return
 	}
+	// only the last function of this chain is allowed to stream (and not any nested calls)
+	ctx = llm.ContextWithStream(ctx, nil)
 	initialValue := input.Value()
-	for _, function := range functions {
-		// if response, err = function.Call(context); err != nil {
-		if response, err = sk.call(input, function); err != nil {
+	for i, function := range functions {
+		if deltas != nil && i == len(functions)-1 {
+			response, err = sk.callStream(ctx, input, function, deltas)
+		} else {
+			response, err = sk.call(ctx, input, function)
+		}
+		if err != nil {
 			err = fmt.Errorf("error calling function `%s`: %w", function.Name, err)
 			return
 		}
@@ -173,29 +350,41 @@ func (sk *SemanticKernel) Call(input llm.Content, functions ...*Function) (respo
 	return
 }
 
+// callStream calls given function with given input and streams its response into deltas
+func (sk *SemanticKernel) callStream(ctx context.Context, input llm.Content, function *Function, deltas chan<- llm.Content) (response llm.Content, err error) {
+	forward := make(chan llm.Content)
+	forwarded := make(chan bool)
+	go func() {
+		sent := false
+		for delta := range forward {
+			sent = true
+			llm.SendDelta(ctx, deltas, delta)
+		}
+		forwarded <- sent
+	}()
+	response, err = sk.call(llm.ContextWithStream(ctx, forward), input, function)
+	close(forward)
+	if sent := <-forwarded; !sent && err == nil {
+		// function didn't stream, so send its complete response
+		err = llm.SendDelta(ctx, deltas, response)
+	}
+	return
+}
+
 // call given function with given input.
-func (sk *SemanticKernel) call(input llm.Content, function *Function) (response llm.Content, err error) {
+func (sk *SemanticKernel) call(ctx context.Context, input llm.Content, function *Function) (response llm.Content, err error) {
 	if function == nil {
 		err = errors.New("function is nil")
 		return
 	}
-	// Check input for required input properties and eventually set default values
-	for _, parameter := range function.InputProperties {
-		if parameter.Default != nil {
-			if input.Property(parameter.Name) == nil {
-				input.With(parameter.Name, parameter.Default)
-			}
-		}
-		if parameter.Required {
-			if input.Property(parameter.Name) == nil {
-				err = errors.Join(err, fmt.Errorf("%w: `%s` (function: %s)", ErrMissingParameter, parameter.Name, function.Name))
-			}
-		}
+	// Validate input properties, eventually set default values and coerce values to their parameter types
+	if err = function.ValidateInput(input); err != nil {
+		return nil, err
 	}
-	if err != nil {
+	// Call function with the kernel and the call stack in context for its templates
+	if ctx, err = contextWithCall(ctx, function); err != nil {
 		return nil, err
 	}
-	// Call function
-	response, err = function.Call(input)
+	response, err = callFunction(ContextWithKernel(ctx, sk), function, input)
 	return
 }
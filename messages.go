@@ -0,0 +1,88 @@
+package gosk
+
+import (
+	"context"
+	"io/fs"
+	"text/template"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+const (
+	// SystemTemplateName is the name of a semantic function's optional system message template
+	SystemTemplateName = "system.tmpl"
+)
+
+// promptMessages are the system message and few-shot examples that precede a semantic function's prompt
+type promptMessages struct {
+	system   *template.Template
+	examples *exampleSelector
+}
+
+// parsePromptMessages finds the system template in the function's templates and parses the function's examples, which
+// are read from the configured examples file or from the default examples file if it exists. Nil is returned if the
+// function has neither of them.
+func parsePromptMessages(fsys fs.FS, promptTemplate *template.Template, config *ExamplesConfig, defaultExamplesFile string, generators map[string]llm.Generator, generatorName string) (messages *promptMessages, err error) {
+	messages = &promptMessages{
+		system: promptTemplate.Lookup(SystemTemplateName),
+	}
+	if config == nil && defaultExamplesFile != "" {
+		if _, statErr := fs.Stat(fsys, defaultExamplesFile); statErr == nil {
+			config = &ExamplesConfig{File: defaultExamplesFile}
+		}
+	}
+	if config != nil {
+		if messages.examples, err = newExampleSelector(fsys, config, generators, generatorName); err != nil {
+			return nil, err
+		}
+	}
+	if messages.system == nil && messages.examples == nil {
+		return nil, nil
+	}
+	return messages, nil
+}
+
+// predecessors renders the system message and returns the message chain with the examples following it
+func (m *promptMessages) predecessors(ctx context.Context, input llm.Content) (last llm.Content, err error) {
+	if m.system != nil {
+		var system string
+		if system, err = ExecuteTemplate(ctx, m.system, input); err != nil {
+			return
+		}
+		last = llm.NewContent(system).SetRole(llm.RoleSystem)
+	}
+	var examples []*Example
+	if m.examples != nil {
+		if examples, err = m.examples.selectExamples(ctx, input); err != nil {
+			return
+		}
+	}
+	for _, example := range examples {
+		exampleInput := llm.NewContent(example.Input).SetRole(llm.RoleUser)
+		if last != nil {
+			exampleInput.WithPredecessor(last)
+		}
+		last = llm.NewContent(example.Output).SetRole(llm.RoleAssistant).WithPredecessor(exampleInput)
+	}
+	return
+}
+
+// withMessages wraps a function call to precede the input with the system message and examples. The messages are only
+// added at the beginning of a conversation, i.e. when the input has no predecessor.
+func (m *promptMessages) withMessages(call func(ctx context.Context, input llm.Content) (llm.Content, error)) func(ctx context.Context, input llm.Content) (llm.Content, error) {
+	return func(ctx context.Context, input llm.Content) (output llm.Content, err error) {
+		if input.Predecessor() == nil {
+			var predecessors llm.Content
+			if predecessors, err = m.predecessors(ctx, input); err != nil {
+				return
+			}
+			// the caller's input isn't modified, e.g. it's the input of further functions of a call chain
+			input = llm.WithoutPredecessor(input)
+			if input.Role() == llm.RoleEmpty {
+				input.SetRole(llm.RoleUser)
+			}
+			input.WithPredecessor(predecessors)
+		}
+		return call(ctx, input)
+	}
+}
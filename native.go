@@ -0,0 +1,169 @@
+package gosk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// NewNativeFunction creates a function from a native Go function with a typed input struct.
+// The function's input properties are derived from the input struct's exported fields and their tags:
+//   - `json:"name"` defines the parameter's name (the name "input" refers to the input content's value)
+//   - `description:"..."` describes the parameter
+//   - `required:"true"` marks the parameter as required
+//   - `enum:"a,b,c"` defines the parameter's allowed values
+//   - `default:"..."` defines the parameter's default value
+//
+// When called, the input content's properties are decoded into the input struct and the native function's
+// result is encoded back into the output content's value. Fields of object results are also set as output properties.
+func NewNativeFunction[In any, Out any](name string, description string, fn func(ctx context.Context, input In) (Out, error)) (function *Function, err error) {
+	inputType := reflect.TypeOf((*In)(nil)).Elem()
+	if inputType.Kind() != reflect.Struct {
+		err = fmt.Errorf("input of native function `%s` must be a struct, got %s", name, inputType)
+		return
+	}
+	inputProperties, err := parametersFromStruct(inputType)
+	if err != nil {
+		err = fmt.Errorf("creating parameters of native function `%s` failed: %w", name, err)
+		return
+	}
+	function = &Function{
+		Name:            name,
+		Description:     description,
+		InputProperties: inputProperties,
+	}
+	function.CallContext = func(ctx context.Context, input llm.Content) (output llm.Content, err error) {
+		var nativeInput In
+		if err = decodeNativeInput(input, inputProperties, &nativeInput); err != nil {
+			err = fmt.Errorf("decoding input of native function `%s` failed: %w", name, err)
+			return
+		}
+		nativeOutput, err := fn(ctx, nativeInput)
+		if err != nil {
+			return
+		}
+		output = llm.NewContent(nativeOutput)
+		// make object fields of the result available as output properties
+		if fields, ok := output.Value().(map[string]interface{}); ok {
+			for name, value := range fields {
+				output.With(name, value)
+			}
+		}
+		return
+	}
+	function.Call = func(input llm.Content) (output llm.Content, err error) {
+		return function.CallContext(context.Background(), input)
+	}
+	return
+}
+
+// parametersFromStruct derives parameters from the struct type's exported fields and their tags
+func parametersFromStruct(structType reflect.Type) (parameters map[string]*Parameter, err error) {
+	parameters = map[string]*Parameter{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		parameter := &Parameter{
+			Description: field.Tag.Get("description"),
+			Type:        typeOf(field.Type),
+		}
+		if required, ok := field.Tag.Lookup("required"); ok {
+			if parameter.Required, err = strconv.ParseBool(required); err != nil {
+				err = fmt.Errorf("invalid required tag of field `%s`: %w", field.Name, err)
+				return
+			}
+		}
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			parameter.Enum = strings.Split(enum, ",")
+		}
+		if defaultValue, ok := field.Tag.Lookup("default"); ok {
+			if parameter.Default, err = parseDefault(defaultValue, parameter.Type); err != nil {
+				err = fmt.Errorf("invalid default tag of field `%s`: %w", field.Name, err)
+				return
+			}
+		}
+		if name == InputPropertyName {
+			// the input content's value
+			name = ""
+		}
+		parameter.Name = name
+		parameters[name] = parameter
+	}
+	return
+}
+
+// typeOf returns the parameter type of a Go type
+func typeOf(t reflect.Type) Type {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeOf(t.Elem())
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return TypeNumber
+	case reflect.Slice, reflect.Array:
+		return TypeArray
+	case reflect.Struct, reflect.Map:
+		return TypeObject
+	}
+	return ""
+}
+
+// parseDefault parses the default tag's value according to the parameter type
+func parseDefault(value string, parameterType Type) (interface{}, error) {
+	switch parameterType {
+	case TypeString, "":
+		return value, nil
+	case TypeBoolean:
+		return strconv.ParseBool(value)
+	case TypeInteger:
+		return strconv.ParseInt(value, 10, 64)
+	case TypeNumber:
+		return strconv.ParseFloat(value, 64)
+	}
+	var defaultValue interface{}
+	err := json.Unmarshal([]byte(value), &defaultValue)
+	return defaultValue, err
+}
+
+// decodeNativeInput decodes the input content's properties into the native input struct
+func decodeNativeInput(input llm.Content, parameters map[string]*Parameter, nativeInput interface{}) error {
+	values := map[string]interface{}{}
+	for name, parameter := range parameters {
+		value := input.Property(name).Value()
+		if value == nil {
+			value = parameter.Default
+		}
+		if value == nil {
+			continue
+		}
+		if name == "" {
+			name = InputPropertyName
+		}
+		values[name] = value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, nativeInput)
+}
@@ -0,0 +1,136 @@
+package gosk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+var (
+	ErrInvalidOutput = errors.New("invalid output")
+)
+
+// OutputError is returned when a function's output doesn't conform to the function's output properties
+type OutputError struct {
+	// Function whose output is invalid
+	Function string
+	// Output is the raw text of the invalid output
+	Output string
+	// Err describes why the output is invalid, e.g. a ValidationError
+	Err error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("%v of function `%s`: %v\nraw output: %s", ErrInvalidOutput, e.Function, e.Err, e.Output)
+}
+
+func (e *OutputError) Unwrap() []error {
+	return []error{ErrInvalidOutput, e.Err}
+}
+
+// ParseOutput parses the output's text as JSON object (also from within fenced code blocks) and validates it against the
+// function's output properties. On success the output's value is set to the parsed object and each of its fields is set
+// as output property. An OutputError with the raw text is returned if the output doesn't conform.
+func (f *Function) ParseOutput(output llm.Content) (err error) {
+	raw := output.String()
+	fields, ok := output.Value().(map[string]interface{})
+	if !ok {
+		var data []byte
+		if data, err = llm.ExtractJSON(raw); err == nil {
+			err = json.Unmarshal(data, &fields)
+		}
+		if err != nil {
+			return &OutputError{Function: f.Name, Output: raw, Err: err}
+		}
+	}
+	if fields == nil {
+		// e.g. `null`
+		return &OutputError{Function: f.Name, Output: raw, Err: errors.New("output is no JSON object")}
+	}
+	parsed := llm.NewContent(fields)
+	for name, value := range fields {
+		parsed.With(name, value)
+	}
+	if validationErr := validateProperties(f.Name, f.OutputProperties, parsed); validationErr != nil {
+		return &OutputError{Function: f.Name, Output: raw, Err: validationErr}
+	}
+	// take over coerced values and defaults
+	for name, property := range parsed.Properties() {
+		fields[name] = property.Value()
+		output.With(name, property.Value())
+	}
+	output.Set(fields)
+	return
+}
+
+// repairPrompt is the follow-up message asking the model to correct its invalid output
+const repairPrompt = "Your previous response is invalid:\n%v\n\nRespond again with only a corrected JSON object."
+
+// RepairError is returned when a function's output is still invalid after all repair attempts
+type RepairError struct {
+	// Function whose output is invalid
+	Function string
+	// Attempts holds the output errors of the initial output and every repair attempt
+	Attempts []*OutputError
+}
+
+func (e *RepairError) Error() string {
+	messages := []string{fmt.Sprintf("output of function `%s` is still invalid after %d repair attempts", e.Function, len(e.Attempts)-1)}
+	for i, attempt := range e.Attempts {
+		messages = append(messages, fmt.Sprintf("attempt %d: %v", i+1, attempt))
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *RepairError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		errs = append(errs, attempt)
+	}
+	return errs
+}
+
+// withOutputParsing wraps a function call to parse its output into the function's output properties. If the function
+// allows repair attempts, the generator is asked to correct invalid output with the output's errors as follow-up message.
+func (f *Function) withOutputParsing(call func(ctx context.Context, input llm.Content) (llm.Content, error), generator llm.Generator) func(ctx context.Context, input llm.Content) (llm.Content, error) {
+	return func(ctx context.Context, input llm.Content) (output llm.Content, err error) {
+		if output, err = call(ctx, input); err != nil || output == nil {
+			return
+		}
+		var outputErr *OutputError
+		if err = f.ParseOutput(output); err == nil || f.RepairAttempts <= 0 || !errors.As(err, &outputErr) {
+			return
+		}
+		repairErr := &RepairError{Function: f.Name, Attempts: []*OutputError{outputErr}}
+		// the repaired output follows the invalid output's predecessor, the repair conversation is omitted
+		predecessor := output.Predecessor()
+		conversation := predecessor
+		if conversation == nil {
+			conversation = input
+		}
+		// repaired output isn't streamed
+		ctx = llm.ContextWithStream(ctx, nil)
+		for attempt := 0; attempt < f.RepairAttempts; attempt++ {
+			invalidOutput := llm.NewContent(outputErr.Output).SetRole(llm.RoleAssistant).WithPredecessor(conversation)
+			conversation = llm.NewContent(fmt.Sprintf(repairPrompt, outputErr.Err)).SetRole(llm.RoleUser).WithPredecessor(invalidOutput)
+			if output, err = llm.GenerateContext(ctx, generator, conversation); err != nil {
+				return nil, errors.Join(repairErr, err)
+			}
+			if err = f.ParseOutput(output); err == nil {
+				if predecessor != nil && output.Predecessor() == nil {
+					output.WithPredecessor(predecessor)
+				}
+				return
+			}
+			if !errors.As(err, &outputErr) {
+				return
+			}
+			repairErr.Attempts = append(repairErr.Attempts, outputErr)
+		}
+		return output, repairErr
+	}
+}
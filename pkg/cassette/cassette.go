@@ -0,0 +1,345 @@
+package cassette
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// Mode defines whether a cassette records, replays or just passes through generations
+type Mode string
+
+const (
+	// ModePassthrough calls the wrapped generators without recording or replaying
+	ModePassthrough Mode = "passthrough"
+	// ModeRecord calls the wrapped generators and records every prompt/response pair
+	ModeRecord Mode = "record"
+	// ModeReplay replays recorded responses without calling the wrapped generators
+	ModeReplay Mode = "replay"
+)
+
+var (
+	// ErrInteractionNotFound is returned in replay mode when no response has been recorded for a request
+	ErrInteractionNotFound = errors.New("interaction not found in cassette")
+	// ErrUnknownMode is returned when a cassette is opened with an unknown mode
+	ErrUnknownMode = errors.New("unknown cassette mode")
+	// ErrNoEmbedder is returned when embeddings are requested from a wrapped generator that can't create them
+	ErrNoEmbedder = errors.New("generator can't create embeddings")
+)
+
+// embeddingsRole is the role of the request message that holds the texts of recorded embeddings
+const embeddingsRole llm.ContentRole = "embeddings"
+
+// Message is the serialized form of a single llm.Content without its predecessors
+type Message struct {
+	Role  llm.ContentRole `json:"role,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Value interface{}     `json:"value"`
+}
+
+// Interaction is a recorded prompt/response pair and represents one line of a cassette file
+type Interaction struct {
+	// Hash of the request that is used to match requests while replaying
+	Hash string `json:"hash"`
+	// Request messages in chronological order (oldest predecessor first)
+	Request []Message `json:"request"`
+	// Response of the generator
+	Response Message `json:"response"`
+}
+
+// Cassette records generations to a JSONL file or replays them from it
+type Cassette struct {
+	mode         Mode
+	path         string
+	mutex        sync.Mutex
+	file         *os.File
+	interactions map[string][]Message
+	replayed     map[string]int
+}
+
+// Open opens the cassette file at path in given mode. In record mode the file is created or truncated,
+// in replay mode all recorded interactions are loaded from it.
+func Open(path string, mode Mode) (cassette *Cassette, err error) {
+	cassette = &Cassette{
+		mode:         mode,
+		path:         path,
+		interactions: map[string][]Message{},
+		replayed:     map[string]int{},
+	}
+	switch mode {
+	case ModePassthrough:
+	case ModeRecord:
+		cassette.file, err = os.Create(path)
+		if err != nil {
+			err = fmt.Errorf("creating cassette `%s` failed: %w", path, err)
+			return nil, err
+		}
+	case ModeReplay:
+		if err = cassette.load(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: `%s`", ErrUnknownMode, mode)
+	}
+	return
+}
+
+// load reads all interactions from the cassette file
+func (c *Cassette) load() error {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("opening cassette `%s` failed: %w", c.path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return fmt.Errorf("reading cassette `%s` failed at line %d: %w", c.path, line, err)
+		}
+		c.interactions[interaction.Hash] = append(c.interactions[interaction.Hash], interaction.Response)
+	}
+	return scanner.Err()
+}
+
+// Mode returns the cassette's mode
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Close closes the cassette file
+func (c *Cassette) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// Wrap wraps given generator so that its generations are recorded or replayed with the cassette.
+// In replay mode the generator may be nil.
+func (c *Cassette) Wrap(generator llm.Generator) *Generator {
+	return &Generator{
+		cassette:  c,
+		generator: generator,
+	}
+}
+
+// WrapRegistration wraps a generator registration so that all generators created by it are wrapped with the cassette.
+// In replay mode no generators are created by the wrapped registration, so that e.g. no API keys are needed.
+func (c *Cassette) WrapRegistration(registrationFunc llm.RegistrationFunc) llm.RegistrationFunc {
+	return func() (string, llm.NewGeneratorFunc) {
+		typeID, newGenerator := registrationFunc()
+		return typeID, func(config llm.GeneratorConfigData) (llm.Generator, error) {
+			if c.mode == ModeReplay {
+				return c.Wrap(nil), nil
+			}
+			generator, err := newGenerator(config)
+			if err != nil {
+				return nil, err
+			}
+			return c.Wrap(generator), nil
+		}
+	}
+}
+
+// replay returns the next recorded response for given request. If a request has been recorded multiple times,
+// the responses are replayed in recorded order and the last one is repeated.
+func (c *Cassette) replay(request []Message) (response llm.Content, err error) {
+	message, err := c.replayMessage(request)
+	if err != nil {
+		return
+	}
+	return messageContent(message), nil
+}
+
+// replayMessage returns the next recorded response message for given request
+func (c *Cassette) replayMessage(request []Message) (response Message, err error) {
+	hash, err := Hash(request)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	responses := c.interactions[hash]
+	if len(responses) <= 0 {
+		err = fmt.Errorf("%w: %s", ErrInteractionNotFound, hash)
+		return
+	}
+	idx := c.replayed[hash]
+	if idx >= len(responses) {
+		idx = len(responses) - 1
+	}
+	c.replayed[hash] = idx + 1
+	return responses[idx], nil
+}
+
+// record appends the interaction to the cassette file
+func (c *Cassette) record(request []Message, response llm.Content) error {
+	return c.recordMessage(request, contentMessage(response))
+}
+
+// recordMessage appends the interaction with given response message to the cassette file
+func (c *Cassette) recordMessage(request []Message, response Message) error {
+	hash, err := Hash(request)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(Interaction{
+		Hash:     hash,
+		Request:  request,
+		Response: response,
+	})
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file == nil {
+		return fmt.Errorf("cassette `%s` is closed", c.path)
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	return err
+}
+
+// Hash returns the stable hash of request messages that is used to match requests while replaying
+func Hash(request []Message) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Request serializes the input content and all its predecessors into request messages in chronological order
+func Request(input llm.Content) (request []Message) {
+	for current := input; current != nil; current = current.Predecessor() {
+		request = append([]Message{contentMessage(current)}, request...)
+	}
+	return
+}
+
+func contentMessage(content llm.Content) Message {
+	return Message{
+		Role:  content.Role(),
+		Name:  content.Name(),
+		Value: content.Value(),
+	}
+}
+
+func messageContent(message Message) llm.Content {
+	content := llm.NewContent(message.Value)
+	if message.Role != llm.RoleEmpty {
+		content.SetRole(message.Role)
+	}
+	if message.Name != "" {
+		content.SetName(message.Name)
+	}
+	return content
+}
+
+// Generator wraps a generator to record or replay its generations with a cassette
+type Generator struct {
+	cassette  *Cassette
+	generator llm.Generator
+}
+
+// Generate records, replays or passes through the generation
+func (g *Generator) Generate(input llm.Content) (response llm.Content, err error) {
+	return g.GenerateContext(context.Background(), input)
+}
+
+// GenerateContext records, replays or passes through the generation with given context
+func (g *Generator) GenerateContext(ctx context.Context, input llm.Content) (response llm.Content, err error) {
+	return g.generate(ctx, input, func(ctx context.Context) (llm.Content, error) {
+		return llm.GenerateContext(llm.ContextWithStream(ctx, nil), g.generator, input)
+	})
+}
+
+// GenerateStream records, replays or passes through the streamed generation. Replayed responses are sent as single delta.
+func (g *Generator) GenerateStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content) (response llm.Content, err error) {
+	return g.generate(ctx, input, func(ctx context.Context) (llm.Content, error) {
+		return llm.GenerateContext(llm.ContextWithStream(ctx, deltas), g.generator, input)
+	}, deltas)
+}
+
+func (g *Generator) generate(ctx context.Context, input llm.Content, generate func(ctx context.Context) (llm.Content, error), deltas ...chan<- llm.Content) (response llm.Content, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	switch g.cassette.mode {
+	case ModeReplay:
+		if response, err = g.cassette.replay(Request(input)); err != nil {
+			return
+		}
+		for _, d := range deltas {
+			if err = llm.SendDelta(ctx, d, response); err != nil {
+				return nil, err
+			}
+		}
+		return
+	case ModeRecord:
+		if response, err = generate(ctx); err != nil {
+			return
+		}
+		err = g.cassette.record(Request(input), response)
+		return
+	default:
+		return generate(ctx)
+	}
+}
+
+// Embed records, replays or passes through the embeddings of the texts. The request is recorded as a single message
+// with the texts and the role "embeddings", the response's value holds the embeddings.
+func (g *Generator) Embed(ctx context.Context, texts []string) (embeddings [][]float64, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	request := []Message{{Role: embeddingsRole, Value: texts}}
+	switch g.cassette.mode {
+	case ModeReplay:
+		var response Message
+		if response, err = g.cassette.replayMessage(request); err != nil {
+			return
+		}
+		var data []byte
+		if data, err = json.Marshal(response.Value); err != nil {
+			return
+		}
+		err = json.Unmarshal(data, &embeddings)
+		return
+	case ModeRecord:
+		if embeddings, err = g.embed(ctx, texts); err != nil {
+			return
+		}
+		err = g.cassette.recordMessage(request, Message{Value: embeddings})
+		return
+	default:
+		return g.embed(ctx, texts)
+	}
+}
+
+// embed creates the embeddings with the wrapped generator
+func (g *Generator) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	embedder, ok := g.generator.(llm.Embedder)
+	if !ok {
+		return nil, ErrNoEmbedder
+	}
+	return embedder.Embed(ctx, texts)
+}
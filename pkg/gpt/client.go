@@ -0,0 +1,80 @@
+package gpt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mfmayer/gopenai"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+const chatCompletionsURL = "https://api.openai.com/v1/chat/completions"
+
+// chatRequest is the request body that is sent to the chat completions endpoint
+type chatRequest struct {
+	*gopenai.ChatPromptConfig
+	Messages  []*gopenai.Message       `json:"messages"`
+	Functions []llm.FunctionDefinition `json:"functions,omitempty"`
+	Stream    bool                     `json:"stream,omitempty"`
+}
+
+// chatCompletion is the response body that is returned by the chat completions endpoint
+type chatCompletion struct {
+	Choices []struct {
+		Message      gopenai.Message `json:"message"`
+		FinishReason string          `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// chatCompletionChunk is a streamed part of the chat completion
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta        gopenai.Message `json:"delta"`
+		FinishReason string          `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// postChat sends the chat request with given context, so that the request is aborted when the context is done
+func (gpt *Generator) postChat(ctx context.Context, request interface{}) (response *http.Response, err error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, chatCompletionsURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Authorization", "Bearer "+gpt.apiKey)
+	return gpt.httpClient.Do(httpRequest)
+}
+
+// getChatCompletion sends the chat request and decodes the completion
+func (gpt *Generator) getChatCompletion(ctx context.Context, request interface{}) (completion *chatCompletion, err error) {
+	response, err := gpt.postChat(ctx, request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	completion = &chatCompletion{}
+	if err = json.NewDecoder(response.Body).Decode(completion); err != nil {
+		if response.StatusCode != http.StatusOK {
+			err = fmt.Errorf("unexpected response status: %s", response.Status)
+		}
+		return nil, err
+	}
+	if completion.Error == nil && response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected response status: %s", response.Status)
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,80 @@
+package gpt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	embeddingsURL         = "https://api.openai.com/v1/embeddings"
+	defaultEmbeddingModel = "text-embedding-ada-002"
+)
+
+// embeddingsRequest is the request body that is sent to the embeddings endpoint
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingsResponse is the response body that is returned by the embeddings endpoint
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Embed creates embedding vectors of the texts with the generator's embedding model (config property "embeddingModel")
+func (gpt *Generator) Embed(ctx context.Context, texts []string) (embeddings [][]float64, err error) {
+	if len(texts) <= 0 {
+		return
+	}
+	model := gpt.options.EmbeddingModel
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	body, err := json.Marshal(&embeddingsRequest{Model: model, Input: texts})
+	if err != nil {
+		return
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, embeddingsURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Authorization", "Bearer "+gpt.apiKey)
+	response, err := gpt.httpClient.Do(httpRequest)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	result := &embeddingsResponse{}
+	decodeErr := json.NewDecoder(response.Body).Decode(result)
+	if response.StatusCode != http.StatusOK {
+		if decodeErr == nil && result.Error != nil {
+			return nil, errors.New(result.Error.Message)
+		}
+		return nil, fmt.Errorf("unexpected response status: %s", response.Status)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decoding embeddings failed: %w", decodeErr)
+	}
+	if result.Error != nil {
+		return nil, errors.New(result.Error.Message)
+	}
+	embeddings = make([][]float64, len(texts))
+	for _, data := range result.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return
+}
@@ -1,10 +1,17 @@
 package gpt
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
 
 	"github.com/mfmayer/gopenai"
 	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/tokenizer"
 )
 
 func Register() (typeID string, newGenerator llm.NewGeneratorFunc) {
@@ -18,32 +25,250 @@ func NewGenerator(config llm.GeneratorConfigData) (generator llm.Generator, err
 	if err != nil {
 		return
 	}
-	chatClient := gopenai.NewChatClient(key)
-	// generatorConfig := gopenai.ChatPromptConfig{}
-	// config.Convert(&generatorConfig)
 	gptGenerator := &Generator{
-		//model:      "gpt-3.5-turbo", // FIXME: should be set by config
 		config:     &gopenai.ChatPromptConfig{},
-		chatClient: chatClient,
+		apiKey:     key,
+		httpClient: http.DefaultClient,
 	}
 	config.Convert(gptGenerator.config)
+	config.Convert(&gptGenerator.options)
+	if gptGenerator.tokenizer, err = newTokenizer(gptGenerator.options.TokenizerFile); err != nil {
+		return
+	}
 	generator = gptGenerator
 	return
 }
 
-// GPT35Generator represents the OpenAI GPT3.5 Model and implements the llm.Generator interface
+// generatorOptions are the generator's config properties besides the chat prompt config
+type generatorOptions struct {
+	// EmbeddingModel is the model that creates embeddings, "text-embedding-ada-002" if not set
+	EmbeddingModel string `json:"embeddingModel,omitempty"`
+	// ContextLimit is the model's context window in tokens, the known limit of the model if not set
+	ContextLimit int `json:"contextLimit,omitempty"`
+	// Truncation defines which messages are dropped if the messages exceed the context window, the oldest non-system
+	// messages if not set
+	Truncation llm.TruncationStrategy `json:"truncation,omitempty"`
+	// KeepLast is the number of latest messages that are kept with the "keepLast" truncation strategy
+	KeepLast int `json:"keepLast,omitempty"`
+	// TokenizerFile is a tiktoken BPE file (e.g. `cl100k_base.tiktoken`) to count tokens, tokens are approximated if not set
+	TokenizerFile string `json:"tokenizerFile,omitempty"`
+}
+
+// contextLimits are the context windows of known models and their snapshots
+var contextLimits = map[string]int{
+	"gpt-3.5-turbo":          4096,
+	"gpt-3.5-turbo-0301":     4096,
+	"gpt-3.5-turbo-0613":     4096,
+	"gpt-3.5-turbo-16k":      16385,
+	"gpt-3.5-turbo-16k-0613": 16385,
+	"gpt-3.5-turbo-1106":     16385,
+	"gpt-3.5-turbo-0125":     16385,
+	"gpt-4":                  8192,
+	"gpt-4-0314":             8192,
+	"gpt-4-0613":             8192,
+	"gpt-4-32k":              32768,
+	"gpt-4-32k-0314":         32768,
+	"gpt-4-32k-0613":         32768,
+	"gpt-4-turbo":            128000,
+	"gpt-4-turbo-preview":    128000,
+	"gpt-4-1106-preview":     128000,
+	"gpt-4-0125-preview":     128000,
+	"gpt-4o":                 128000,
+	"gpt-4o-mini":            128000,
+}
+
+// contextLimit returns the context window of the model. Unknown snapshots get the window of the model with the longest
+// matching name prefix (e.g. `gpt-4o-2024-05-13` the one of `gpt-4o`), 0 is returned if the model is unknown.
+func contextLimit(model string) (limit int) {
+	if limit, ok := contextLimits[model]; ok {
+		return limit
+	}
+	prefixLength := 0
+	for prefix, prefixLimit := range contextLimits {
+		if strings.HasPrefix(model, prefix+"-") && len(prefix) > prefixLength {
+			limit, prefixLength = prefixLimit, len(prefix)
+		}
+	}
+	return
+}
+
+// newTokenizer reads the tokenizer file, or returns an approximating tokenizer if there's no file
+func newTokenizer(file string) (llm.Tokenizer, error) {
+	if file == "" {
+		return llm.ApproximateTokenizer{}, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bpe, err := tokenizer.ReadBPE(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading tokenizer `%s` failed: %w", file, err)
+	}
+	return bpe, nil
+}
+
+const (
+	// tokensPerMessage are the tokens each message needs in addition to its content
+	tokensPerMessage = 3
+	// tokensPerReply are the tokens that prime the model's reply
+	tokensPerReply = 3
+)
+
+// truncate drops messages of the input's conversation that exceed the model's context window, which is reduced by the
+// tokens of the response and the functions that are advertised to the model
+func (gpt *Generator) truncate(input llm.Content, functions []llm.FunctionDefinition) (llm.Content, error) {
+	limit := gpt.options.ContextLimit
+	if limit <= 0 {
+		limit = contextLimit(gpt.config.Model)
+	}
+	if limit <= 0 {
+		return input, nil
+	}
+	var tokenizer llm.Tokenizer = llm.ApproximateTokenizer{}
+	if gpt.tokenizer != nil {
+		tokenizer = gpt.tokenizer
+	}
+	limit -= gpt.config.MaxTokens + tokensPerReply
+	if len(functions) > 0 {
+		definitions, _ := json.Marshal(functions)
+		limit -= tokenizer.CountTokens(string(definitions))
+	}
+	window := llm.ContextWindow{
+		Tokenizer:        tokenizer,
+		MaxTokens:        limit,
+		Strategy:         gpt.options.Truncation,
+		KeepLast:         gpt.options.KeepLast,
+		TokensPerContent: tokensPerMessage,
+	}
+	if window.MaxTokens <= 0 {
+		return nil, fmt.Errorf("%w: no tokens left for messages", llm.ErrContextWindowExceeded)
+	}
+	return window.Truncate(input)
+}
+
+// Generator represents the OpenAI GPT chat models and implements the llm.Generator, llm.ContextGenerator,
+// llm.StreamingGenerator and llm.Embedder interfaces
 type Generator struct {
 	config     *gopenai.ChatPromptConfig
-	chatClient *gopenai.ChatClient
+	options    generatorOptions
+	tokenizer  llm.Tokenizer
+	apiKey     string
+	httpClient *http.Client
 }
 
-// GenerateResponse to get response from the model
+// Generate to get response from the model
 func (gpt *Generator) Generate(input llm.Content) (response llm.Content, err error) {
-	if gpt.chatClient == nil {
+	return gpt.GenerateContext(context.Background(), input)
+}
+
+// GenerateContext to get response from the model. The request to the model is aborted when the context is done.
+// Functions set with llm.ContextWithFunctions are advertised to the model, which may respond with a function call.
+func (gpt *Generator) GenerateContext(ctx context.Context, input llm.Content) (response llm.Content, err error) {
+	if gpt.httpClient == nil {
+		err = errors.New("missing model client")
+		return
+	}
+
+	// create chat request with the functions that are advertised to the model
+	functions, _ := llm.FunctionsFromContext(ctx)
+	if input, err = gpt.truncate(input, functions); err != nil {
+		return
+	}
+	request := chatRequest{
+		ChatPromptConfig: gpt.config,
+		Messages:         contentMessages(input),
+		Functions:        functions,
+	}
+	// get response
+	completion, err := gpt.getChatCompletion(ctx, &request)
+	if err != nil {
+		return
+	}
+	if completion.Error != nil {
+		err = errors.New(completion.Error.Message)
+		return
+	}
+	if len(completion.Choices) <= 0 {
+		err = errors.New("no response available")
+		return
+	}
+	response = Message2Content(&completion.Choices[0].Message)
+	return
+}
+
+// GenerateStream to get the response from the model token by token. Partial response deltas are sent into
+// the deltas channel as soon as they are received and the complete response is returned at the end.
+func (gpt *Generator) GenerateStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content) (response llm.Content, err error) {
+	if gpt.httpClient == nil {
 		err = errors.New("missing model client")
 		return
 	}
 
+	// create streaming chat request with the functions that are advertised to the model
+	functions, _ := llm.FunctionsFromContext(ctx)
+	if input, err = gpt.truncate(input, functions); err != nil {
+		return
+	}
+	request := chatRequest{
+		ChatPromptConfig: gpt.config,
+		Messages:         contentMessages(input),
+		Functions:        functions,
+		Stream:           true,
+	}
+	httpResponse, err := gpt.postChat(ctx, &request)
+	if err != nil {
+		return
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		// errors are returned as plain completion
+		completion := chatCompletion{}
+		if decodeErr := json.NewDecoder(httpResponse.Body).Decode(&completion); decodeErr == nil && completion.Error != nil {
+			err = errors.New(completion.Error.Message)
+			return
+		}
+		err = fmt.Errorf("unexpected response status: %s", httpResponse.Status)
+		return
+	}
+
+	// accumulate streamed message
+	message := gopenai.Message{Role: gopenai.RoleAssistant}
+	err = readEvents(httpResponse.Body, func(data []byte) error {
+		chunk := chatCompletionChunk{}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return err
+		}
+		if chunk.Error != nil {
+			return errors.New(chunk.Error.Message)
+		}
+		if len(chunk.Choices) <= 0 {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.FunctionCall != nil {
+			if message.FunctionCall == nil {
+				message.FunctionCall = &gopenai.FunctionCall{}
+			}
+			message.FunctionCall.Name += delta.FunctionCall.Name
+			message.FunctionCall.Arguments += delta.FunctionCall.Arguments
+		}
+		if delta.Content == "" {
+			return nil
+		}
+		message.Content += delta.Content
+		return llm.SendDelta(ctx, deltas, llm.NewContent(delta.Content).SetRole(llm.RoleAssistant))
+	})
+	if err != nil {
+		return
+	}
+	response = Message2Content(&message)
+	return
+}
+
+// contentMessages translates the input and all its predecessors into messages in chronological order
+func contentMessages(input llm.Content) (messages []*gopenai.Message) {
 	// get all predecessors and append them to input slice
 	inputSlice := []llm.Content{input}
 	predecessor := input.Predecessor()
@@ -54,31 +279,13 @@ func (gpt *Generator) Generate(input llm.Content) (response llm.Content, err err
 		inputSlice = append(inputSlice, predecessor)
 		predecessor = predecessor.Predecessor()
 	}
-
-	// create chat prompt
-	chatPrompt := gopenai.ChatPrompt{
-		ChatPromptConfig: gpt.config,
-		Messages:         make([]*gopenai.Message, 0, len(inputSlice)),
-	}
+	messages = make([]*gopenai.Message, 0, len(inputSlice))
 	// iterate over input slice in reverse order to get the correct order of messages
 	for i := len(inputSlice) - 1; i >= 0; i-- {
 		inputElement := inputSlice[i]
 		if msg, err := Content2Message(inputElement); err == nil {
-			chatPrompt.Messages = append(chatPrompt.Messages, msg)
+			messages = append(messages, msg)
 		}
 	}
-	// get response
-	completion, err := gpt.chatClient.GetChatCompletion(&chatPrompt)
-	if err != nil {
-		return
-	}
-	if completion.Error != nil {
-		err = errors.New(completion.Error.Message)
-		return
-	}
-	if len(completion.Choices) <= 0 {
-		err = errors.New("no resposne available")
-	}
-	response = Message2Content(&completion.Choices[0].Message)
 	return
 }
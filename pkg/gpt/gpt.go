@@ -48,7 +48,7 @@ func Content2Message(content llm.Content) (msg *gopenai.Message, err error) {
 	}
 	if role == llm.RoleFunctionCall {
 		msg.FunctionCall = &gopenai.FunctionCall{}
-		msg.FunctionCall.Arguments = string(content.JSON())
+		msg.FunctionCall.Arguments = content.String()
 		if name := content.Name(); name != "" {
 			msg.FunctionCall.Name = name
 		} else {
@@ -0,0 +1,53 @@
+package gpt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// readEvents reads server-sent events from r and calls onData with each event's data until the
+// stream ends, the "[DONE]" event is received or onData returns an error
+func readEvents(r io.Reader, onData func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data []byte
+	dispatch := func() (done bool, err error) {
+		if len(data) <= 0 {
+			return
+		}
+		defer func() { data = data[:0] }()
+		if bytes.Equal(data, []byte("[DONE]")) {
+			return true, nil
+		}
+		return false, onData(data)
+	}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			// empty line dispatches the event
+			if done, err := dispatch(); done || err != nil {
+				return err
+			}
+			continue
+		}
+		if line[0] == ':' {
+			// comment
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		if !bytes.Equal(field, []byte("data")) {
+			continue
+		}
+		value = bytes.TrimPrefix(value, []byte(" "))
+		if len(data) > 0 {
+			data = append(data, '\n')
+		}
+		data = append(data, value...)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err := dispatch()
+	return err
+}
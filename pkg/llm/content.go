@@ -44,9 +44,19 @@ type ContentProperty interface {
 
 type Content interface {
 	ContentProperty
-	// With sets a property at given path and is a shortcut for Property(path).Set(value)
+	// With sets a property at given path and is a shortcut for Property(path).Set(value).
+	// Paths separate properties by dots and can address list elements by index (`items.0`, `items[-1]`), append to lists
+	// (`items[]`) and set all elements (`items.*.done`). A leading dot addresses the content's value (`.items`).
+	// An index right after a list's last element appends to the list, other indices outside the list leave it unchanged
+	// and an index of a missing property creates a list. Lists and maps on the path are copied instead of modified, so
+	// values that have been passed to With aren't changed. A wildcard at the root only sets the content's properties
+	// (but not its value, role, name and predecessor) and appending at the root is ignored, as the content isn't a list.
 	With(path string, value interface{}) Content
-	// Prop returns the content's property at given path, nil if not available
+	// Delete deletes the property at given path from the content (but not from its predecessors). A wildcard at the root
+	// only deletes the content's properties.
+	Delete(path string) Content
+	// Prop returns the content's property at given path, nil if not available.
+	// A wildcard path (`items.*.name`) returns the values of all elements as list.
 	Property(path string) ContentProperty
 	// Properties returns all properties of the content and its predecessors
 	Properties() map[string]ContentProperty
@@ -95,35 +105,14 @@ func (c content) value(path string) interface{} {
 		}
 		return v
 	}
-	// return value at path
-	currentContent := c
-FINDVALUE:
-	var current interface{} = map[string]interface{}(currentContent)
-	for nextPathPart, remainingPath := getNextPathPart(path); nextPathPart != ""; nextPathPart, remainingPath = getNextPathPart(remainingPath) {
-		// path not yet complete
-		currentMap, ok := current.(map[string]interface{})
-		if !ok {
-			// patch incomplete and current is not a map --> property not found in this content object
-			current = nil
-			break
-		}
-		// get next
-		current, ok = currentMap[nextPathPart]
-		if !ok {
-			// patch incomplete and next element doesn't exist --> property not found in this content object
-			current = nil
-			break
-		}
-	}
-	if current == nil {
-		predecessor, ok := currentContent.Predecessor().(content)
-		if ok {
-			currentContent = predecessor
-			goto FINDVALUE
+	// return value at path of this content object or its predecessors
+	parts := pathParts(path)
+	for currentContent, ok := c, true; ok && currentContent != nil; currentContent, ok = currentContent.Predecessor().(content) {
+		if value, found := valueAt(map[string]interface{}(currentContent), parts); found && value != nil {
+			return value
 		}
 	}
-	// path complete
-	return current
+	return nil
 }
 
 // string returns the string value at given path from this content object or its predecessors.
@@ -180,34 +169,52 @@ func (c content) With(path string, value interface{}) Content {
 		return c
 	}
 	// set value at path
-	var currentMap map[string]interface{} = c
-	for nextPathPart, remainingPath := getNextPathPart(path); nextPathPart != "" || remainingPath != ""; nextPathPart, remainingPath = getNextPathPart(remainingPath) {
-		// Last path part? --> Set value
-		if len(remainingPath) <= 0 {
-			setValue(currentMap, nextPathPart, value)
-			break
+	switch parts := pathParts(path); parts[0] {
+	case pathWildcard:
+		for key, element := range c {
+			if reservedKey(key) {
+				continue
+			}
+			if element, ok := setNext(element, parts, value); ok {
+				c[key] = element
+			}
 		}
-		// get next
-		next, ok := currentMap[nextPathPart]
-		if !ok {
-			// next element doesn't exist --> create map and set it
-			nextMap := map[string]interface{}{}
-			currentMap[nextPathPart] = nextMap
-			currentMap = nextMap
-			continue
+	case pathAppend:
+		// the content itself isn't a list
+	default:
+		setProperty(c, parts, value)
+	}
+	return c
+}
+
+// reservedKey returns whether the content's key holds its value, role, name or predecessor instead of a property
+func reservedKey(key string) bool {
+	switch key {
+	case "", "role", "name", "predecessor":
+		return true
+	}
+	return false
+}
+
+func (c content) Delete(path string) Content {
+	if c == nil {
+		return nil
+	}
+	parts := pathParts(path)
+	switch {
+	case len(parts) == 0:
+		delete(c, "")
+	case parts[0] == pathWildcard:
+		for key, element := range c {
+			if !reservedKey(key) {
+				deleteProperty(c, key, element, parts)
+			}
 		}
-		nextMap, ok := next.(map[string]interface{})
-		if !ok {
-			// next element is not a map --> create map and replace it
-			nextMap := map[string]interface{}{}
-			currentMap[nextPathPart] = nextMap
-			currentMap = nextMap
-			continue
+	default:
+		if element, ok := c[parts[0]]; ok {
+			deleteProperty(c, parts[0], element, parts)
 		}
-		// next element is already a map, set as current map
-		currentMap = nextMap
 	}
-	// path complete
 	return c
 }
 
@@ -253,11 +260,13 @@ func (c content) Role() ContentRole {
 	if !ok {
 		return RoleEmpty
 	}
-	role, ok := iRole.(ContentRole)
-	if !ok {
-		return RoleEmpty
+	switch role := iRole.(type) {
+	case ContentRole:
+		return role
+	case string:
+		return ContentRole(role)
 	}
-	return role
+	return RoleEmpty
 }
 
 func (c content) SetName(name string) Content {
@@ -288,6 +297,55 @@ func (c content) JSON() []byte {
 	return marshalledValue
 }
 
+// UnmarshalContent unmarshals content that has been marshalled with its JSON method. The content is a JSON object with
+// its value at key "" and its properties at their names. The optional "role" and "name" keys hold the content's role and
+// name and the "predecessor" key holds the preceding content of a conversation in the same format:
+//
+//	{"": "Hi, how are you?", "role": "user", "predecessor": {"": "You are a helpful bot.", "role": "system"}}
+//
+// The predecessor chain, roles, names and nested properties are restored. As with all JSON, numbers are restored as float64.
+func UnmarshalContent(data []byte) (Content, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("invalid content: %s", data)
+	}
+	return contentFromMap(m)
+}
+
+// contentFromMap converts an unmarshalled map and its predecessors to content
+func contentFromMap(m map[string]interface{}) (Content, error) {
+	c := content(m)
+	if iRole, ok := c["role"]; ok {
+		role, ok := iRole.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid content role: %v", iRole)
+		}
+		c["role"] = ContentRole(role)
+	}
+	if iName, ok := c["name"]; ok {
+		if _, ok := iName.(string); !ok {
+			return nil, fmt.Errorf("invalid content name: %v", iName)
+		}
+	}
+	if iPredecessor, ok := c["predecessor"]; ok && iPredecessor == nil {
+		delete(c, "predecessor")
+	} else if ok {
+		predecessorMap, ok := iPredecessor.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid content predecessor: %v", iPredecessor)
+		}
+		predecessor, err := contentFromMap(predecessorMap)
+		if err != nil {
+			return nil, err
+		}
+		c["predecessor"] = predecessor
+	}
+	return c, nil
+}
+
 func (c content) WithPredecessor(content Content) Content {
 	c["predecessor"] = content
 	return c
@@ -304,6 +362,42 @@ func (c content) Predecessor() Content {
 	return nil
 }
 
+// Chain returns the content and its predecessors in chronological order, i.e. the oldest predecessor first
+func Chain(c Content) (chain []Content) {
+	for current := c; current != nil; current = current.Predecessor() {
+		chain = append(chain, current)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return
+}
+
+// WithoutPredecessor returns a shallow copy of the content without its predecessor, e.g. to store a conversation's
+// contents one by one
+func WithoutPredecessor(c Content) Content {
+	if c == nil {
+		return nil
+	}
+	m, ok := c.(content)
+	if !ok {
+		// other implementations are copied by their JSON representation
+		if err := json.Unmarshal(c.JSON(), &m); err != nil {
+			return nil
+		}
+	}
+	copied := content{}
+	for k, v := range m {
+		if k != "predecessor" {
+			copied[k] = v
+		}
+	}
+	if role := c.Role(); role != RoleEmpty {
+		copied["role"] = role
+	}
+	return copied
+}
+
 type contentEntry struct {
 	path string
 	cm   content
@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder is implemented by generators that can create embedding vectors of texts, e.g. to find similar texts
+type Embedder interface {
+	// Embed returns an embedding vector for each of the texts in the same order
+	Embed(ctx context.Context, texts []string) (embeddings [][]float64, err error)
+}
+
+// CosineSimilarity returns the cosine similarity of two embedding vectors, 0 if one of them is a zero vector
+func CosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// MaxFunctionCalls limits the number of consecutive function calls that are executed by GenerateContext
+// before the model has to produce a final answer
+const MaxFunctionCalls = 10
+
+var (
+	ErrTooManyFunctionCalls = errors.New("too many function calls")
+)
+
+// FunctionDefinition describes a function that can be advertised to the model behind a generator
+type FunctionDefinition struct {
+	// Name of the function as the model has to use it in a function call
+	Name string `json:"name"`
+	// Description what the function is doing
+	Description string `json:"description,omitempty"`
+	// Parameters of the function as JSON schema object
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// FunctionExecutor executes a function call requested by the model. The call content has the role RoleFunctionCall,
+// its name is the function's name and its value holds the function's arguments. The executor's response value is
+// passed back to the model as function response.
+type FunctionExecutor func(ctx context.Context, call Content) (response Content, err error)
+
+type functionsContextKey struct{}
+
+type contextFunctions struct {
+	definitions []FunctionDefinition
+	executor    FunctionExecutor
+}
+
+// ContextWithFunctions returns a context that advertises the function definitions to generators that support function calling.
+// If executor is not nil, GenerateContext executes the function calls requested by the model with it and passes
+// the function responses back to the model until it produces a final answer.
+func ContextWithFunctions(ctx context.Context, definitions []FunctionDefinition, executor FunctionExecutor) context.Context {
+	return context.WithValue(ctx, functionsContextKey{}, contextFunctions{
+		definitions: definitions,
+		executor:    executor,
+	})
+}
+
+// FunctionsFromContext returns the function definitions and executor set with ContextWithFunctions
+func FunctionsFromContext(ctx context.Context) (definitions []FunctionDefinition, executor FunctionExecutor) {
+	functions, _ := ctx.Value(functionsContextKey{}).(contextFunctions)
+	return functions.definitions, functions.executor
+}
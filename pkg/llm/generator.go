@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,6 +25,111 @@ type Generator interface {
 	Generate(input Content) (response Content, err error)
 }
 
+// ContextGenerator is implemented by generators that support cancellation and deadlines via context
+type ContextGenerator interface {
+	Generator
+	// GenerateContext to get response from the model behind the generator with given context
+	GenerateContext(ctx context.Context, input Content) (response Content, err error)
+}
+
+// StreamingGenerator is implemented by generators that can deliver their response token by token
+type StreamingGenerator interface {
+	Generator
+	// GenerateStream sends partial response deltas to the deltas channel while the response is generated
+	// and returns the complete response. The deltas channel is owned by the caller and not closed.
+	GenerateStream(ctx context.Context, input Content, deltas chan<- Content) (response Content, err error)
+}
+
+type streamContextKey struct{}
+
+// ContextWithStream returns a context that requests GenerateContext to stream response deltas into given channel.
+// A nil channel disables streaming for the returned context.
+func ContextWithStream(ctx context.Context, deltas chan<- Content) context.Context {
+	return context.WithValue(ctx, streamContextKey{}, deltas)
+}
+
+// StreamFromContext returns the deltas channel set with ContextWithStream, nil if streaming isn't requested
+func StreamFromContext(ctx context.Context) chan<- Content {
+	deltas, _ := ctx.Value(streamContextKey{}).(chan<- Content)
+	return deltas
+}
+
+// GenerateContext gets a response from given generator with given context. If the generator doesn't
+// implement ContextGenerator, the context is only checked before Generate is called.
+// If streaming is requested with ContextWithStream, streaming generators send their deltas into the
+// stream's channel while other generators send their complete response as single delta.
+// If functions with executor are set with ContextWithFunctions, function calls requested by the model are executed
+// and their responses are passed back to the model until it produces a final answer. The final response's predecessors
+// are the function calls and responses.
+func GenerateContext(ctx context.Context, generator Generator, input Content) (response Content, err error) {
+	if response, err = generate(ctx, generator, input); err != nil {
+		return
+	}
+	_, executor := FunctionsFromContext(ctx)
+	if executor == nil {
+		return
+	}
+	for calls := 0; response.Role() == RoleFunctionCall; calls++ {
+		if calls >= MaxFunctionCalls {
+			err = fmt.Errorf("%w: %d", ErrTooManyFunctionCalls, calls)
+			return nil, err
+		}
+		call := response.WithPredecessor(input)
+		var result Content
+		if result, err = executor(ctx, call); err != nil {
+			err = fmt.Errorf("executing function `%s` failed: %w", call.Name(), err)
+			return nil, err
+		}
+		input = NewContent(result.Value()).
+			SetRole(RoleFunctionResponse).
+			SetName(call.Name()).
+			WithPredecessor(call)
+		if response, err = generate(ctx, generator, input); err != nil {
+			return
+		}
+		if response.Role() != RoleFunctionCall {
+			response.WithPredecessor(input)
+		}
+	}
+	return
+}
+
+// generate gets a single response from given generator
+func generate(ctx context.Context, generator Generator, input Content) (response Content, err error) {
+	if generator == nil {
+		err = ErrMissingGenerator
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	deltas := StreamFromContext(ctx)
+	if deltas != nil {
+		if streamingGenerator, ok := generator.(StreamingGenerator); ok {
+			return streamingGenerator.GenerateStream(ctx, input, deltas)
+		}
+	}
+	if contextGenerator, ok := generator.(ContextGenerator); ok {
+		response, err = contextGenerator.GenerateContext(ctx, input)
+	} else {
+		response, err = generator.Generate(input)
+	}
+	if err == nil && deltas != nil && response.Role() != RoleFunctionCall {
+		err = SendDelta(ctx, deltas, response)
+	}
+	return
+}
+
+// SendDelta sends a delta into the deltas channel unless the context is done before
+func SendDelta(ctx context.Context, deltas chan<- Content, delta Content) error {
+	select {
+	case deltas <- delta:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // GeneratorConfig to configure a specific generator's (defined by ID) response generator
 type GeneratorConfig struct {
 	TypeID           string              `json:"typeID"`
@@ -80,4 +186,5 @@ func (gm NewGeneratorFuncMap) CreateGenerators(generatorConfigs map[string]Gener
 
 var (
 	ErrUnknownGeneratorType = errors.New("unknown generator type")
+	ErrMissingGenerator     = errors.New("missing generator")
 )
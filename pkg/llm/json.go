@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+var (
+	ErrNoJSON = errors.New("no JSON found")
+)
+
+// fencedCodeBlock matches markdown code blocks with optional language, e.g. ```json ... ```
+var fencedCodeBlock = regexp.MustCompile("(?s)```[\\w-]*[ \\t]*\\r?\\n(.*?)```")
+
+// ExtractJSON extracts JSON from generated text. The whole text, the content of fenced code blocks and finally the
+// text between the first opening and the last closing brace (or bracket) are tried, in this order.
+func ExtractJSON(text string) (data []byte, err error) {
+	candidates := [][]byte{[]byte(text)}
+	for _, match := range fencedCodeBlock.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, []byte(match[1]))
+	}
+	for _, delimiters := range []string{"{}", "[]"} {
+		start := bytes.IndexByte([]byte(text), delimiters[0])
+		end := bytes.LastIndexByte([]byte(text), delimiters[1])
+		if start >= 0 && end > start {
+			candidates = append(candidates, []byte(text[start:end+1]))
+		}
+	}
+	for _, candidate := range candidates {
+		candidate = bytes.TrimSpace(candidate)
+		if len(candidate) > 0 && json.Valid(candidate) {
+			return candidate, nil
+		}
+	}
+	return nil, ErrNoJSON
+}
@@ -2,28 +2,67 @@ package llm
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"text/template"
+	"text/template/parse"
 )
 
+// PromptTemplateName is the name of a semantic function's prompt template
+const PromptTemplateName = "skprompt.tmpl"
+
+var (
+	ErrMissingTemplate = errors.New("missing template")
+)
+
+// TemplateFromFS parses the templates matching the patterns with the registered template functions and returns "skprompt.tmpl"
 func TemplateFromFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
-	template, err := template.ParseFS(fsys, patterns...)
+	template, err := TemplatesFromFS(fsys, patterns...)
 	if err != nil {
 		return nil, err
 	}
-	promptTemplate := template.Lookup("skprompt.tmpl")
+	promptTemplate := template.Lookup(PromptTemplateName)
 	if promptTemplate != nil {
 		return promptTemplate, nil
 	}
-	return template, fmt.Errorf("\"skprompt.tmpl\" not found")
+	return template, fmt.Errorf("\"%s\" not found", PromptTemplateName)
+}
+
+// TemplatesFromFS parses the templates matching the patterns with the registered template functions, e.g. to be used as partials
+func TemplatesFromFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
+	return template.New("").Funcs(TemplateFuncs()).ParseFS(fsys, patterns...)
 }
 
+// TemplateFromText parses the text with the registered template functions
 func TemplateFromText(text string) (*template.Template, error) {
-	return template.New("skprompt").Parse(text)
+	return template.New("skprompt").Funcs(TemplateFuncs()).Parse(text)
+}
+
+// AddTemplates adds templates (e.g. shared partials) to the template's set unless the set already defines them
+func AddTemplates(template *template.Template, templates *template.Template) error {
+	if templates == nil {
+		return nil
+	}
+	for _, partial := range templates.Templates() {
+		if partial.Tree == nil {
+			continue
+		}
+		if defined := template.Lookup(partial.Name()); defined != nil && defined.Tree != nil {
+			continue
+		}
+		if _, err := template.AddParseTree(partial.Name(), partial.Tree); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// ExecuteTemplate executes the template and returns an ErrMissingTemplate error if it references undefined templates
 func ExecuteTemplate(template *template.Template, data interface{}) (string, error) {
+	if err := checkTemplateReferences(template, template, map[string]bool{}); err != nil {
+		return "", err
+	}
 	var buf bytes.Buffer
 	err := template.Execute(&buf, data)
 	if err != nil {
@@ -32,6 +71,50 @@ func ExecuteTemplate(template *template.Template, data interface{}) (string, err
 	return buf.String(), err
 }
 
+// checkTemplateReferences checks that all templates referenced by given template (and by those) are defined in the set
+func checkTemplateReferences(set *template.Template, referencing *template.Template, checked map[string]bool) (err error) {
+	checked[referencing.Name()] = true
+	if referencing.Tree == nil {
+		return nil
+	}
+	walkTemplateNodes(referencing.Tree.Root, func(node *parse.TemplateNode) {
+		if err != nil || checked[node.Name] {
+			return
+		}
+		referenced := set.Lookup(node.Name)
+		if referenced == nil || referenced.Tree == nil {
+			err = fmt.Errorf("%w: template `%s` referenced by `%s` is not defined", ErrMissingTemplate, node.Name, referencing.Name())
+			return
+		}
+		err = checkTemplateReferences(set, referenced, checked)
+	})
+	return
+}
+
+// walkTemplateNodes calls fn for every template node ({{template "name"}}) in the tree
+func walkTemplateNodes(node parse.Node, fn func(node *parse.TemplateNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateNodes(child, fn)
+		}
+	case *parse.IfNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.TemplateNode:
+		fn(n)
+	}
+}
+
 func ApplyTemplateToContent(template *template.Template, content Content) error {
 	text, err := ExecuteTemplate(template, content)
 	if err != nil {
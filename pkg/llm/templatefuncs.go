@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	templateFuncsMutex sync.RWMutex
+	templateFuncs      = template.FuncMap{
+		"json":       toJSON,
+		"toYaml":     toYAML,
+		"join":       join,
+		"upper":      func(value interface{}) string { return strings.ToUpper(toString(value)) },
+		"lower":      func(value interface{}) string { return strings.ToLower(toString(value)) },
+		"truncate":   truncate,
+		"default":    defaultValue,
+		"indent":     indent,
+		"now":        time.Now,
+		"formatDate": formatDate,
+	}
+)
+
+// RegisterTemplateFuncs registers functions that are available in all prompt templates parsed afterwards.
+// Functions with the name of an already registered function replace it.
+func RegisterTemplateFuncs(funcs template.FuncMap) {
+	templateFuncsMutex.Lock()
+	defer templateFuncsMutex.Unlock()
+	for name, function := range funcs {
+		templateFuncs[name] = function
+	}
+}
+
+// TemplateFuncs returns a copy of the functions that are available in prompt templates
+func TemplateFuncs() template.FuncMap {
+	templateFuncsMutex.RLock()
+	defer templateFuncsMutex.RUnlock()
+	funcs := make(template.FuncMap, len(templateFuncs))
+	for name, function := range templateFuncs {
+		funcs[name] = function
+	}
+	return funcs
+}
+
+// unwrap returns the value of content properties and the value itself otherwise
+func unwrap(value interface{}) interface{} {
+	if property, ok := value.(ContentProperty); ok {
+		return property.Value()
+	}
+	return value
+}
+
+// toString returns the value's string representation as it is inserted into a template
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// toJSON marshals the value to JSON, e.g. {{json .}} or {{.Property "list" | json}}
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(unwrap(value))
+	return string(data), err
+}
+
+// toYAML marshals the value to YAML, e.g. {{toYaml .}}
+func toYAML(value interface{}) (string, error) {
+	data, err := yaml.Marshal(unwrap(value))
+	return strings.TrimSuffix(string(data), "\n"), err
+}
+
+// join joins the list's items with given separator, e.g. {{.Property "tags" | join ", "}}
+func join(separator string, list interface{}) string {
+	v := reflect.ValueOf(unwrap(list))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return toString(unwrap(list))
+	}
+	items := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, toString(v.Index(i).Interface()))
+	}
+	return strings.Join(items, separator)
+}
+
+// truncate truncates the text to given number of characters, e.g. {{.Input | truncate 100}}
+func truncate(length int, value interface{}) string {
+	text := toString(value)
+	runes := []rune(text)
+	if length < 0 || len(runes) <= length {
+		return text
+	}
+	return string(runes[:length])
+}
+
+// defaultValue returns the default if the value is empty, e.g. {{.Property "language" | default "English"}}
+func defaultValue(defaultValue interface{}, value interface{}) interface{} {
+	v := reflect.ValueOf(unwrap(value))
+	if !v.IsValid() || v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+		return defaultValue
+	}
+	return value
+}
+
+// indent indents every line of the text by given number of spaces, e.g. {{toYaml . | indent 2}}
+func indent(spaces int, value interface{}) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(toString(value), "\n", "\n"+padding)
+}
+
+// formatDate formats a time or RFC 3339 time string with given Go layout, e.g. {{now | formatDate "2006-01-02"}}
+func formatDate(layout string, value interface{}) (string, error) {
+	switch v := unwrap(value).(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("formatDate: unsupported value %v", value)
+}
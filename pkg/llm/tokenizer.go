@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of texts the way a model does, e.g. to fit a conversation into the model's context window
+type Tokenizer interface {
+	// CountTokens returns the number of tokens of the text
+	CountTokens(text string) int
+}
+
+// ApproximateTokenizer estimates the number of tokens with four characters per token, which is a rule of thumb for
+// english texts and OpenAI's tokenizers. It's used if no exact tokenizer is available.
+type ApproximateTokenizer struct{}
+
+// CountTokens returns the estimated number of tokens of the text
+func (ApproximateTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// TruncationStrategy defines which contents of a conversation are dropped if it exceeds a context window
+type TruncationStrategy string
+
+const (
+	// TruncateNone doesn't drop any contents
+	TruncateNone TruncationStrategy = "none"
+	// TruncateOldest drops the oldest contents except system contents
+	TruncateOldest TruncationStrategy = "dropOldest"
+	// TruncateKeepFirstSystem drops the oldest contents except the first system content
+	TruncateKeepFirstSystem TruncationStrategy = "keepFirstSystem"
+	// TruncateKeepLast keeps only the first system content and the last N contents and drops the oldest of them if
+	// they still exceed the context window
+	TruncateKeepLast TruncationStrategy = "keepLast"
+)
+
+// ErrContextWindowExceeded is returned if a conversation can't be truncated to fit into a context window
+var ErrContextWindowExceeded = errors.New("context window exceeded")
+
+// ContextWindow truncates conversations (contents with their predecessors) to a maximum number of tokens
+type ContextWindow struct {
+	// Tokenizer counts the tokens of the contents, ApproximateTokenizer if not set
+	Tokenizer Tokenizer
+	// MaxTokens is the maximum number of tokens of a conversation, unlimited if 0
+	MaxTokens int
+	// Strategy defines which contents are dropped, TruncateOldest if not set
+	Strategy TruncationStrategy
+	// KeepLast is the number of latest contents that are kept with TruncateKeepLast
+	KeepLast int
+	// TokensPerContent are the tokens that are needed for each content in addition to its text, e.g. for its role
+	TokensPerContent int
+}
+
+// CountTokens returns the number of tokens of the content without its predecessors
+func (w *ContextWindow) CountTokens(content Content) int {
+	var tokenizer Tokenizer = ApproximateTokenizer{}
+	if w.Tokenizer != nil {
+		tokenizer = w.Tokenizer
+	}
+	tokens := w.TokensPerContent + tokenizer.CountTokens(content.String())
+	if name := content.Name(); name != "" {
+		tokens += tokenizer.CountTokens(name)
+	}
+	return tokens
+}
+
+// CountConversationTokens returns the number of tokens of the content and its predecessors
+func (w *ContextWindow) CountConversationTokens(content Content) (tokens int) {
+	for current := content; current != nil; current = current.Predecessor() {
+		tokens += w.CountTokens(current)
+	}
+	return
+}
+
+// Truncate drops contents of the conversation according to the window's strategy until it fits into the window. The
+// last content (the input) is never dropped, function calls are dropped together with their responses. The
+// conversation isn't modified, a truncated copy of it is returned.
+func (w *ContextWindow) Truncate(input Content) (truncated Content, err error) {
+	if input == nil || w.Strategy == TruncateNone {
+		return input, nil
+	}
+	contents := Chain(input)
+	firstSystem := -1
+	for i, content := range contents {
+		if content.Role() == RoleSystem {
+			firstSystem = i
+			break
+		}
+	}
+	// droppable returns whether the strategy allows to drop the content at given index
+	droppable := func(i int) bool {
+		switch w.Strategy {
+		case TruncateKeepFirstSystem, TruncateKeepLast:
+			return i != firstSystem
+		}
+		return contents[i].Role() != RoleSystem
+	}
+
+	// partner returns the index of the function call's response or of the function response's call, -1 if there is none
+	partner := func(i int) int {
+		switch {
+		case contents[i].Role() == RoleFunctionCall && i+1 < len(contents) && contents[i+1].Role() == RoleFunctionResponse:
+			return i + 1
+		case contents[i].Role() == RoleFunctionResponse && i > 0 && contents[i-1].Role() == RoleFunctionCall:
+			return i - 1
+		}
+		return -1
+	}
+
+	keep := make([]bool, len(contents))
+	for i := range contents {
+		keep[i] = w.Strategy != TruncateKeepLast || i >= len(contents)-w.KeepLast || i == len(contents)-1 || !droppable(i)
+	}
+	// function calls and their responses are kept together
+	for i := range contents {
+		if p := partner(i); p >= 0 && keep[p] {
+			keep[i] = true
+		}
+	}
+	tokens := 0
+	for i, content := range contents {
+		if keep[i] {
+			tokens += w.CountTokens(content)
+		}
+	}
+	for i := 0; w.MaxTokens > 0 && tokens > w.MaxTokens && i < len(contents)-1; i++ {
+		p := partner(i)
+		if !keep[i] || !droppable(i) || p == len(contents)-1 {
+			continue
+		}
+		keep[i] = false
+		tokens -= w.CountTokens(contents[i])
+		if p >= 0 && keep[p] {
+			keep[p] = false
+			tokens -= w.CountTokens(contents[p])
+		}
+	}
+	if w.MaxTokens > 0 && tokens > w.MaxTokens {
+		return nil, fmt.Errorf("%w: %d tokens exceed %d tokens", ErrContextWindowExceeded, tokens, w.MaxTokens)
+	}
+
+	// copy the kept contents, unless all contents are kept
+	dropped := false
+	for i := range contents {
+		if !keep[i] {
+			dropped = true
+			break
+		}
+	}
+	if !dropped {
+		return input, nil
+	}
+	for i, content := range contents {
+		if !keep[i] {
+			continue
+		}
+		copied := WithoutPredecessor(content)
+		if truncated != nil {
+			copied.WithPredecessor(truncated)
+		}
+		truncated = copied
+	}
+	return
+}
@@ -3,21 +3,321 @@ package llm
 import (
 	"encoding/json"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-func getNextPathPart(path string) (part string, remainingPath string) {
-	idx := strings.Index(path, ".")
-	if idx == -1 {
-		return path, ""
+const (
+	// pathWildcard is a path part that matches all elements of a list or map
+	pathWildcard = "*"
+	// pathAppend is a path part that appends an element to a list
+	pathAppend = "[]"
+)
+
+// pathParts splits a path into its parts. Parts are separated by dots, list indices can also be given in brackets, e.g.
+// `items[2].name` is split into "items", "2" and "name" and `items[]` into "items" and "[]". A leading dot refers to the
+// content's value, i.e. the first part of `.items` is "".
+func pathParts(path string) (parts []string) {
+	if strings.HasPrefix(path, ".") {
+		parts = append(parts, "")
 	}
-	part = path[0:idx]
-	if idx < len(path)-1 {
-		remainingPath = path[idx+1:]
+	for _, part := range strings.Split(path, ".") {
+		for {
+			open := strings.Index(part, "[")
+			close := strings.Index(part, "]")
+			if open < 0 || close < open {
+				break
+			}
+			if open > 0 {
+				parts = append(parts, part[:open])
+			}
+			if index := part[open+1 : close]; index == "" {
+				parts = append(parts, pathAppend)
+			} else {
+				parts = append(parts, index)
+			}
+			part = part[close+1:]
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
 	}
 	return
 }
 
+// listIndex parses a list index, negative indices count from the end of the list
+func listIndex(part string, length int) (index int, ok bool) {
+	index, err := strconv.Atoi(part)
+	if err != nil {
+		return 0, false
+	}
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0
+}
+
+// list returns current as list if it is a slice or array
+func list(current interface{}) (elements []interface{}, ok bool) {
+	if elements, ok = current.([]interface{}); ok {
+		return
+	}
+	value := reflect.ValueOf(current)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, false
+	}
+	elements = make([]interface{}, value.Len())
+	for i := range elements {
+		elements[i] = value.Index(i).Interface()
+	}
+	return elements, true
+}
+
+// mapOf returns current as map if it is a map or content
+func mapOf(current interface{}) (m map[string]interface{}, ok bool) {
+	switch current := current.(type) {
+	case map[string]interface{}:
+		return current, true
+	case content:
+		return current, true
+	}
+	return nil, false
+}
+
+// elements returns the elements of a list or the values of a map in the order of their keys
+func elements(current interface{}) (elements []interface{}, ok bool) {
+	if elements, ok = list(current); ok {
+		return
+	}
+	m, ok := mapOf(current)
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		elements = append(elements, m[key])
+	}
+	return elements, true
+}
+
+// valueAt returns the value at the path's parts of current. A wildcard part collects the values at the remaining parts
+// of all elements into a list, which is flattened for further wildcards.
+func valueAt(current interface{}, parts []string) (value interface{}, ok bool) {
+	for i, part := range parts {
+		if part == pathWildcard {
+			all, ok := elements(current)
+			if !ok {
+				return nil, false
+			}
+			values := []interface{}{}
+			nested := containsWildcard(parts[i+1:])
+			for _, element := range all {
+				value, ok := valueAt(element, parts[i+1:])
+				if !ok {
+					continue
+				}
+				if nested {
+					values = append(values, value.([]interface{})...)
+					continue
+				}
+				values = append(values, value)
+			}
+			return values, true
+		}
+		if m, ok := mapOf(current); ok {
+			if current, ok = m[part]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		elements, ok := list(current)
+		if !ok {
+			return nil, false
+		}
+		index, ok := listIndex(part, len(elements))
+		if !ok || index >= len(elements) {
+			return nil, false
+		}
+		current = elements[index]
+	}
+	return current, true
+}
+
+// containsWildcard returns whether one of the parts is a wildcard
+func containsWildcard(parts []string) bool {
+	for _, part := range parts {
+		if part == pathWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// copyMap returns a shallow copy of the map or content m as the same type as current
+func copyMap(current interface{}, m map[string]interface{}) (copied map[string]interface{}, typed interface{}) {
+	copied = make(map[string]interface{}, len(m)+1)
+	for key, value := range m {
+		copied[key] = value
+	}
+	if _, ok := current.(content); ok {
+		return copied, content(copied)
+	}
+	return copied, copied
+}
+
+// setAt sets the value at the path's parts of current and returns a copy of current with the value and whether it has
+// been set. The maps and lists on the path are copied instead of modified. Missing maps and lists (for index and
+// appending parts) are created, current is replaced if it can't hold the parts. An invalid index, i.e. one that is
+// neither in the list nor right after its end, leaves the list unchanged. A wildcard part sets the value at the
+// remaining parts of all elements.
+func setAt(current interface{}, parts []string, value interface{}) (interface{}, bool) {
+	part := parts[0]
+	if elements, ok := list(current); ok {
+		return setElement(current, elements, parts, value)
+	}
+	m, ok := mapOf(current)
+	if !ok {
+		if _, err := strconv.Atoi(part); err == nil || part == pathAppend {
+			return setElement(current, nil, parts, value)
+		}
+		m = map[string]interface{}{}
+	}
+	if part == pathAppend {
+		// maps can't be appended to
+		return current, false
+	}
+	m, typed := copyMap(current, m)
+	if part == pathWildcard {
+		set := false
+		for key, element := range m {
+			if element, ok := setNext(element, parts, value); ok {
+				m[key], set = element, true
+			}
+		}
+		if !set {
+			return current, false
+		}
+		return typed, true
+	}
+	if !setProperty(m, parts, value) {
+		return current, false
+	}
+	return typed, true
+}
+
+// setProperty sets the value at the path's parts of map m, whose first part is a key of m
+func setProperty(m map[string]interface{}, parts []string, value interface{}) bool {
+	if len(parts) == 1 {
+		setValue(m, parts[0], value)
+		return true
+	}
+	element, ok := setAt(m[parts[0]], parts[1:], value)
+	if ok {
+		m[parts[0]] = element
+	}
+	return ok
+}
+
+// setElement sets the value at the path's parts of the list's elements and returns a copy of the list with the value.
+// Current is returned unchanged if the first part isn't a valid index.
+func setElement(current interface{}, elements []interface{}, parts []string, value interface{}) (interface{}, bool) {
+	part := parts[0]
+	if part == pathAppend {
+		part = strconv.Itoa(len(elements))
+	}
+	copied := append(make([]interface{}, 0, len(elements)+1), elements...)
+	if part == pathWildcard {
+		for i, element := range copied {
+			if element, ok := setNext(element, parts, value); ok {
+				copied[i] = element
+			}
+		}
+		return copied, true
+	}
+	index, ok := listIndex(part, len(copied))
+	if !ok || index > len(copied) {
+		return current, false
+	}
+	if index == len(copied) {
+		copied = append(copied, nil)
+	}
+	if copied[index], ok = setNext(copied[index], parts, value); !ok {
+		return current, false
+	}
+	return copied, true
+}
+
+// setNext sets the value at the parts following the first part of element, or returns the value if there are none
+func setNext(element interface{}, parts []string, value interface{}) (interface{}, bool) {
+	if len(parts) == 1 {
+		return normalizeValue(value), true
+	}
+	return setAt(element, parts[1:], value)
+}
+
+// deleteAt deletes the value at the path's parts of current and returns a copy of current without the value. The maps
+// and lists on the path are copied instead of modified. A wildcard part deletes the value at the remaining parts of all
+// elements.
+func deleteAt(current interface{}, parts []string) interface{} {
+	part, last := parts[0], len(parts) == 1
+	if m, ok := mapOf(current); ok {
+		if part != pathWildcard {
+			if _, ok := m[part]; !ok {
+				return current
+			}
+		}
+		m, typed := copyMap(current, m)
+		if part != pathWildcard {
+			deleteProperty(m, part, m[part], parts)
+			return typed
+		}
+		for key, element := range m {
+			deleteProperty(m, key, element, parts)
+		}
+		return typed
+	}
+	elements, ok := list(current)
+	if !ok {
+		return current
+	}
+	if part == pathWildcard {
+		if last {
+			return []interface{}{}
+		}
+		copied := make([]interface{}, len(elements))
+		for i, element := range elements {
+			copied[i] = deleteAt(element, parts[1:])
+		}
+		return copied
+	}
+	index, ok := listIndex(part, len(elements))
+	if !ok || index >= len(elements) {
+		return current
+	}
+	if last {
+		copied := make([]interface{}, 0, len(elements)-1)
+		return append(append(copied, elements[:index]...), elements[index+1:]...)
+	}
+	copied := append([]interface{}(nil), elements...)
+	copied[index] = deleteAt(copied[index], parts[1:])
+	return copied
+}
+
+// deleteProperty deletes the map's element with given key if parts has no further parts, otherwise the value at the
+// further parts of the element
+func deleteProperty(m map[string]interface{}, key string, element interface{}, parts []string) {
+	if len(parts) == 1 {
+		delete(m, key)
+		return
+	}
+	m[key] = deleteAt(element, parts[1:])
+}
+
 func convertToMap(i interface{}) (map[string]interface{}, error) {
 	data, err := json.Marshal(i)
 	if err != nil {
@@ -33,25 +333,29 @@ func convertToMap(i interface{}) (map[string]interface{}, error) {
 }
 
 func setValue(m map[string]interface{}, key string, value interface{}) {
+	m[key] = normalizeValue(value)
+}
+
+// normalizeValue parses JSON object strings and converts structs to maps, so that their fields can be accessed by path
+func normalizeValue(value interface{}) interface{} {
 	if valueString, ok := value.(string); ok {
 		var valueMap map[string]interface{}
 		if err := json.Unmarshal([]byte(valueString), &valueMap); err == nil {
-			m[key] = valueMap
-			return
+			return valueMap
 		}
-		m[key] = valueString
-		return
+		return valueString
 	}
 	t := reflect.TypeOf(value)
+	if t == nil {
+		return nil
+	}
 	switch t.Kind() {
 	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64:
-		m[key] = value
-		return
+		return value
 	}
 	valueMap, err := convertToMap(value)
 	if err == nil {
-		m[key] = valueMap
-		return
+		return valueMap
 	}
-	m[key] = value
+	return value
 }
@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// EmbeddingDimensions is the number of dimensions of the mock generator's embedding vectors
+const EmbeddingDimensions = 64
+
+// Embed creates deterministic bag-of-words embeddings: every lower-cased word is hashed into one of the vector's
+// dimensions, so that texts sharing words are similar.
+func (g *Generator) Embed(ctx context.Context, texts []string) (embeddings [][]float64, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	embeddings = make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		embedding := make([]float64, EmbeddingDimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, word := range words {
+			hash := fnv.New32a()
+			hash.Write([]byte(word))
+			embedding[hash.Sum32()%EmbeddingDimensions]++
+		}
+		embeddings = append(embeddings, embedding)
+	}
+	return
+}
@@ -0,0 +1,241 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// TypeID is the generator type ID the mock generator is registered with by Register
+const TypeID = "mock"
+
+var (
+	// ErrNoResponse is returned when neither a rule matches nor a canned response or default response is left
+	ErrNoResponse = errors.New("no mock response available")
+)
+
+// Call records a single generation of the mock generator
+type Call struct {
+	// Config of the generator that has been called (as configured for the skill)
+	Config llm.GeneratorConfigData
+	// Input content the generator has been called with (incl. predecessors)
+	Input llm.Content
+	// Prompt is the rendered prompt, i.e. the input content's string value
+	Prompt string
+	// Functions that have been advertised with llm.ContextWithFunctions
+	Functions []llm.FunctionDefinition
+	// Response returned by the generator, nil in case of an error
+	Response llm.Content
+	// Err returned by the generator
+	Err error
+}
+
+// ResponseFunc creates a response for given input
+type ResponseFunc func(input llm.Content) (response llm.Content, err error)
+
+type rule struct {
+	pattern  *regexp.Regexp
+	response ResponseFunc
+}
+
+// Generator is a deterministic generator for offline tests. It answers with responses of rules whose pattern
+// matches the rendered prompt, with canned responses in given order or with a default response.
+// Every call is recorded and can be asserted with Calls.
+type Generator struct {
+	mutex           sync.Mutex
+	rules           []rule
+	responses       []string
+	defaultResponse ResponseFunc
+	calls           []Call
+}
+
+// New creates a new mock generator without any responses
+func New() *Generator {
+	return &Generator{}
+}
+
+// Respond appends canned responses that are returned one after the other when no rule matches
+func (g *Generator) Respond(responses ...string) *Generator {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.responses = append(g.responses, responses...)
+	return g
+}
+
+// When adds a rule that responds with given response when the rendered prompt matches the regular expression pattern.
+// Rules are checked in the order they have been added and take precedence over canned responses.
+func (g *Generator) When(pattern string, response string) *Generator {
+	return g.WhenFunc(pattern, func(input llm.Content) (llm.Content, error) {
+		return llm.NewContent(response), nil
+	})
+}
+
+// WhenFunc adds a rule like When that creates the response with given response function
+func (g *Generator) WhenFunc(pattern string, response ResponseFunc) *Generator {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.rules = append(g.rules, rule{
+		pattern:  regexp.MustCompile(pattern),
+		response: response,
+	})
+	return g
+}
+
+// Default sets the response that is returned when no rule matches and no canned response is left
+func (g *Generator) Default(response string) *Generator {
+	return g.DefaultFunc(func(input llm.Content) (llm.Content, error) {
+		return llm.NewContent(response), nil
+	})
+}
+
+// DefaultFunc sets the response function that is used when no rule matches and no canned response is left
+func (g *Generator) DefaultFunc(response ResponseFunc) *Generator {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.defaultResponse = response
+	return g
+}
+
+// Echo sets the default response to the rendered prompt
+func (g *Generator) Echo() *Generator {
+	return g.DefaultFunc(func(input llm.Content) (llm.Content, error) {
+		return llm.NewContent(input.String()), nil
+	})
+}
+
+// Calls returns the recorded calls in chronological order
+func (g *Generator) Calls() []Call {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	calls := make([]Call, len(g.calls))
+	copy(calls, g.calls)
+	return calls
+}
+
+// Reset removes all rules, responses and recorded calls
+func (g *Generator) Reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.rules = nil
+	g.responses = nil
+	g.defaultResponse = nil
+	g.calls = nil
+}
+
+// Register registers the mock generator with its own type ID "mock"
+func (g *Generator) Register() (typeID string, newGenerator llm.NewGeneratorFunc) {
+	return g.RegisterAs(TypeID)()
+}
+
+// RegisterAs returns a registration function that registers the mock generator with given type ID.
+// This allows to redirect skill configs that reference e.g. `"typeID": "gpt"` to the mock generator.
+func (g *Generator) RegisterAs(typeID string) llm.RegistrationFunc {
+	return func() (string, llm.NewGeneratorFunc) {
+		return typeID, func(config llm.GeneratorConfigData) (llm.Generator, error) {
+			return &configuredGenerator{Generator: g, config: config}, nil
+		}
+	}
+}
+
+// Generate responds to given input
+func (g *Generator) Generate(input llm.Content) (response llm.Content, err error) {
+	return g.generate(context.Background(), nil, input)
+}
+
+// GenerateContext responds to given input unless the context is done
+func (g *Generator) GenerateContext(ctx context.Context, input llm.Content) (response llm.Content, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return g.generate(ctx, nil, input)
+}
+
+// GenerateStream responds to given input and streams the response word by word
+func (g *Generator) GenerateStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content) (response llm.Content, err error) {
+	return g.generateStream(ctx, nil, input, deltas)
+}
+
+func (g *Generator) generateStream(ctx context.Context, config llm.GeneratorConfigData, input llm.Content, deltas chan<- llm.Content) (response llm.Content, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if response, err = g.generate(ctx, config, input); err != nil {
+		return
+	}
+	for _, word := range strings.SplitAfter(response.String(), " ") {
+		if err = llm.SendDelta(ctx, deltas, llm.NewContent(word).SetRole(llm.RoleAssistant)); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// generate finds the response for given input and records the call
+func (g *Generator) generate(ctx context.Context, config llm.GeneratorConfigData, input llm.Content) (response llm.Content, err error) {
+	responseFunc := g.nextResponse(input)
+	if responseFunc == nil {
+		err = ErrNoResponse
+	} else {
+		response, err = responseFunc(input)
+	}
+	if response != nil && response.Role() == llm.RoleEmpty {
+		response.SetRole(llm.RoleAssistant)
+	}
+	functions, _ := llm.FunctionsFromContext(ctx)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.calls = append(g.calls, Call{
+		Config:    config,
+		Input:     input,
+		Prompt:    input.String(),
+		Functions: functions,
+		Response:  response,
+		Err:       err,
+	})
+	return
+}
+
+// nextResponse returns the response function of the first matching rule, the next canned response or the default response
+func (g *Generator) nextResponse(input llm.Content) ResponseFunc {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	prompt := input.String()
+	for _, rule := range g.rules {
+		if rule.pattern.MatchString(prompt) {
+			return rule.response
+		}
+	}
+	if len(g.responses) > 0 {
+		response := g.responses[0]
+		g.responses = g.responses[1:]
+		return func(input llm.Content) (llm.Content, error) {
+			return llm.NewContent(response), nil
+		}
+	}
+	return g.defaultResponse
+}
+
+// configuredGenerator is a mock generator that has been created for a skill's generator config
+type configuredGenerator struct {
+	*Generator
+	config llm.GeneratorConfigData
+}
+
+func (cg *configuredGenerator) Generate(input llm.Content) (response llm.Content, err error) {
+	return cg.generate(context.Background(), cg.config, input)
+}
+
+func (cg *configuredGenerator) GenerateContext(ctx context.Context, input llm.Content) (response llm.Content, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return cg.generate(ctx, cg.config, input)
+}
+
+func (cg *configuredGenerator) GenerateStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content) (response llm.Content, err error) {
+	return cg.generateStream(ctx, cg.config, input, deltas)
+}
@@ -0,0 +1,251 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+var (
+	// ErrInvalidPlan is returned when a plan doesn't match the kernel's plannable functions
+	ErrInvalidPlan = errors.New("invalid plan")
+)
+
+// Status of a plan's step
+type Status string
+
+const (
+	// StatusPending indicates that the step hasn't been executed yet (or has to be executed again)
+	StatusPending Status = "pending"
+	// StatusSucceeded indicates that the step has been executed successfully and its output is available
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed indicates that the step's execution has failed
+	StatusFailed Status = "failed"
+)
+
+// Plan to accomplish a goal with a sequence of function calls.
+// Plans can be marshalled to JSON incl. the steps' status and outputs, stored, edited and executed later.
+type Plan struct {
+	// Goal that shall be accomplished with the plan
+	Goal string `json:"goal,omitempty"`
+	// Steps that are executed one after the other
+	Steps []*Step `json:"steps"`
+}
+
+// Step of a plan that calls a single function
+type Step struct {
+	// ID of the step that is used by following steps to reference the step's output
+	ID string `json:"id"`
+	// Function that is called with path notation (`skillName.functionName`)
+	Function string `json:"function"`
+	// Inputs of the function with their names as keys ("input" is the function's main input)
+	Inputs map[string]*Binding `json:"inputs,omitempty"`
+	// Status of the step, empty status is treated as pending
+	Status Status `json:"status,omitempty"`
+	// Error message of the step's failed execution
+	Error string `json:"error,omitempty"`
+	// Output of the step after it has been executed successfully
+	Output llm.Content `json:"-"`
+}
+
+// stepJSON is used to marshal and unmarshal a step with its output content
+type stepJSON struct {
+	*stepAlias
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+type stepAlias Step
+
+// MarshalJSON marshals the step with its output content, i.e. the output's value and properties (incl. the properties
+// of its predecessors) without its predecessors
+func (s *Step) MarshalJSON() ([]byte, error) {
+	step := stepJSON{stepAlias: (*stepAlias)(s)}
+	if s.Output != nil {
+		output := llm.WithoutPredecessor(s.Output)
+		for name, property := range s.Output.Properties() {
+			switch name {
+			case "role", "name", "predecessor":
+				continue
+			}
+			output.With(name, property.Value())
+		}
+		step.Output = output.JSON()
+	}
+	return json.Marshal(step)
+}
+
+// UnmarshalJSON unmarshals the step and restores its output content. Outputs that aren't marshalled as content (i.e.
+// without the value at key "") are restored as output value.
+func (s *Step) UnmarshalJSON(data []byte) (err error) {
+	step := stepJSON{stepAlias: (*stepAlias)(s)}
+	if err = json.Unmarshal(data, &step); err != nil {
+		return
+	}
+	s.Output = nil
+	if len(step.Output) == 0 {
+		return
+	}
+	var output interface{}
+	if err = json.Unmarshal(step.Output, &output); err != nil || output == nil {
+		return
+	}
+	if m, ok := output.(map[string]interface{}); ok {
+		if _, ok := m[""]; ok {
+			s.Output, err = llm.UnmarshalContent(step.Output)
+			return
+		}
+	}
+	s.Output = llm.NewContent(output)
+	return
+}
+
+// done returns true if the step has succeeded and its output can be reused
+func (s *Step) done() bool {
+	return s.Status == StatusSucceeded && s.Output != nil
+}
+
+// Binding binds a step's input either to a literal value or to the output of a previous step
+type Binding struct {
+	// Value is the binding's literal value
+	Value interface{} `json:"value,omitempty"`
+	// Ref is the ID of the previous step whose output is used
+	Ref string `json:"ref,omitempty"`
+	// Path of the referenced output's property (e.g. `results.0.title` or `results.*.title`), empty to use the output's value
+	Path string `json:"path,omitempty"`
+}
+
+// Step returns the plan's step with given ID, nil if not found
+func (p *Plan) Step(id string) *Step {
+	for _, step := range p.Steps {
+		if step.ID == id {
+			return step
+		}
+	}
+	return nil
+}
+
+// Done returns true if all steps of the plan have succeeded
+func (p *Plan) Done() bool {
+	for _, step := range p.Steps {
+		if !step.done() {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset resets all steps to pending and removes their outputs and errors, so that the whole plan is executed again
+func (p *Plan) Reset() {
+	for _, step := range p.Steps {
+		step.Status = StatusPending
+		step.Error = ""
+		step.Output = nil
+	}
+}
+
+// Validate checks that all steps call plannable functions of the kernel, that all references point
+// to previous steps and that all required inputs are bound
+func (p *Plan) Validate(kernel *gosk.SemanticKernel) (err error) {
+	functions := PlannableFunctions(kernel)
+	ids := map[string]bool{}
+	for i, step := range p.Steps {
+		if step.ID == "" {
+			err = errors.Join(err, fmt.Errorf("%w: step %d has no id", ErrInvalidPlan, i+1))
+		} else if ids[step.ID] {
+			err = errors.Join(err, fmt.Errorf("%w: step id `%s` is not unique", ErrInvalidPlan, step.ID))
+		}
+		switch step.Status {
+		case "", StatusPending, StatusSucceeded, StatusFailed:
+		default:
+			err = errors.Join(err, fmt.Errorf("%w: step `%s` has unknown status `%s`", ErrInvalidPlan, step.ID, step.Status))
+		}
+		function, ok := functions[step.Function]
+		if !ok {
+			err = errors.Join(err, fmt.Errorf("%w: function `%s` of step `%s` is not plannable", ErrInvalidPlan, step.Function, step.ID))
+		}
+		for name, binding := range step.Inputs {
+			if binding == nil {
+				err = errors.Join(err, fmt.Errorf("%w: input `%s` of step `%s` is not bound", ErrInvalidPlan, name, step.ID))
+				continue
+			}
+			if binding.Ref != "" && !ids[binding.Ref] {
+				err = errors.Join(err, fmt.Errorf("%w: input `%s` of step `%s` references unknown previous step `%s`", ErrInvalidPlan, name, step.ID, binding.Ref))
+			}
+		}
+		if function != nil {
+			for parameterName, parameter := range function.InputProperties {
+				if !parameter.Required || parameter.Default != nil {
+					continue
+				}
+				name := parameterName
+				if name == "" {
+					name = gosk.InputPropertyName
+				}
+				if _, ok := step.Inputs[name]; !ok {
+					err = errors.Join(err, fmt.Errorf("%w: required input `%s` of step `%s` is not bound", ErrInvalidPlan, name, step.ID))
+				}
+			}
+		}
+		ids[step.ID] = true
+	}
+	return
+}
+
+// Execute validates the plan and calls the functions of its steps one after the other with the kernel.
+// Steps that have already succeeded are skipped and their outputs are reused, so that a failed plan is resumed
+// from its first failed step. Each step's status and output is stored in the step and the last step's output is returned.
+func (p *Plan) Execute(ctx context.Context, kernel *gosk.SemanticKernel) (response llm.Content, err error) {
+	if err = p.Validate(kernel); err != nil {
+		return
+	}
+	for _, step := range p.Steps {
+		if !step.done() {
+			if err = p.execute(ctx, kernel, step); err != nil {
+				err = fmt.Errorf("executing step `%s` failed: %w", step.ID, err)
+				return
+			}
+		}
+		response = step.Output
+	}
+	return
+}
+
+// execute calls the step's function and updates the step's status and output
+func (p *Plan) execute(ctx context.Context, kernel *gosk.SemanticKernel, step *Step) (err error) {
+	defer func() {
+		if err != nil {
+			step.Status = StatusFailed
+			step.Error = err.Error()
+			step.Output = nil
+			return
+		}
+		step.Status = StatusSucceeded
+		step.Error = ""
+	}()
+	functions, err := kernel.FindFunctions(step.Function)
+	if err != nil {
+		return
+	}
+	arguments := map[string]interface{}{}
+	for name, binding := range step.Inputs {
+		arguments[name] = p.resolve(binding)
+	}
+	step.Output, err = kernel.CallContext(ctx, functions[0].InputFromArguments(arguments), functions[0])
+	return
+}
+
+// resolve returns the binding's literal value or the referenced step's output value
+func (p *Plan) resolve(binding *Binding) interface{} {
+	if binding.Ref == "" {
+		return binding.Value
+	}
+	step := p.Step(binding.Ref)
+	if step == nil || step.Output == nil {
+		return nil
+	}
+	return step.Output.Property(binding.Path).Value()
+}
@@ -0,0 +1,116 @@
+package planner
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"text/template"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+//go:embed assets/*
+var fsAssets embed.FS
+
+// Planner creates plans to accomplish goals with the plannable functions of a kernel
+type Planner struct {
+	kernel         *gosk.SemanticKernel
+	generator      llm.Generator
+	promptTemplate *template.Template
+}
+
+// New creates a planner for the kernel's plannable functions that uses given generator to create plans
+func New(kernel *gosk.SemanticKernel, generator llm.Generator) (planner *Planner, err error) {
+	promptTemplate, err := llm.TemplateFromFS(fsAssets, "assets/*.tmpl")
+	if err != nil {
+		return
+	}
+	planner = &Planner{
+		kernel:         kernel,
+		generator:      generator,
+		promptTemplate: promptTemplate,
+	}
+	return
+}
+
+// PlannableFunctions returns the kernel's plannable functions with their paths (`skillName.functionName`) as keys.
+// Functions are plannable if they and their skill are plannable.
+func PlannableFunctions(kernel *gosk.SemanticKernel) (functions map[string]*gosk.Function) {
+	functions = map[string]*gosk.Function{}
+	for _, skill := range kernel.Skills() {
+		if !skill.Plannable {
+			continue
+		}
+		for name, function := range skill.Functions {
+			if function.Plannable {
+				functions[skill.Name+"."+name] = function
+			}
+		}
+	}
+	return
+}
+
+// catalogue returns the definitions of all plannable functions as JSON
+func (p *Planner) catalogue() (string, error) {
+	functions := PlannableFunctions(p.kernel)
+	definitions := make([]llm.FunctionDefinition, 0, len(functions))
+	for path, function := range functions {
+		definitions = append(definitions, function.Definition(path))
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	data, err := json.MarshalIndent(definitions, "", "  ")
+	return string(data), err
+}
+
+// CreatePlan asks the model for a plan to accomplish the goal with the kernel's plannable functions and validates it
+func (p *Planner) CreatePlan(ctx context.Context, goal string) (plan *Plan, err error) {
+	catalogue, err := p.catalogue()
+	if err != nil {
+		return
+	}
+	prompt, err := llm.ExecuteTemplate(p.promptTemplate, llm.NewContent(goal).With("functions", catalogue))
+	if err != nil {
+		return
+	}
+	response, err := llm.GenerateContext(ctx, p.generator, llm.NewContent(prompt).SetRole(llm.RoleUser))
+	if err != nil {
+		return
+	}
+	plan, err = ParsePlan(response.String())
+	if err != nil {
+		return
+	}
+	plan.Goal = goal
+	err = plan.Validate(p.kernel)
+	return
+}
+
+// ParsePlan parses a plan from the model's response text (also from within fenced code blocks). Steps without ID get their index based ID (e.g. "step1").
+func ParsePlan(text string) (plan *Plan, err error) {
+	data, err := llm.ExtractJSON(text)
+	if err != nil {
+		err = errors.Join(ErrInvalidPlan, err)
+		return
+	}
+	plan = &Plan{}
+	if err = json.Unmarshal(data, plan); err != nil {
+		err = errors.Join(ErrInvalidPlan, err)
+		return nil, err
+	}
+	for i, step := range plan.Steps {
+		if step == nil {
+			err = fmt.Errorf("%w: step %d is empty", ErrInvalidPlan, i+1)
+			return nil, err
+		}
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("step%d", i+1)
+		}
+	}
+	return
+}
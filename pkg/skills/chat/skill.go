@@ -1,31 +1,134 @@
 package chat
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"io/fs"
 	"text/template"
 
 	"github.com/mfmayer/gosk"
 	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/store"
 )
 
 //go:embed assets/*
 var fsAssets embed.FS
 
+// SessionProperty is the input property with the ID of the session whose conversation is continued
+const SessionProperty = "session"
+
+// options of the chat skill's conversations
+type options struct {
+	conversations store.ConversationStore
+	summary       *summaryOptions
+}
+
+// Option configures how the chat skill handles conversations
+type Option func(*options)
+
+// WithStore persists conversations in given store. If the input of the `chatgpt` function has a `session` property, the
+// session's conversation is loaded as the input's predecessors and the new contents of the conversation are stored. If
+// the input already has predecessors, the conversation with them replaces the session's stored conversation.
+func WithStore(conversations store.ConversationStore) Option {
+	return func(o *options) {
+		o.conversations = conversations
+	}
+}
+
+// RegisterWith returns a registration function for the chat skill with given options
+func RegisterWith(opts ...Option) gosk.SkillRegistrationFunc {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(generatorFactories llm.NewGeneratorFuncMap) (skill *gosk.Skill, err error) {
+		if skill, err = Register(generatorFactories); err != nil {
+			return
+		}
+		if o.conversations == nil && o.summary == nil {
+			return
+		}
+		function := skill.Functions["chatgpt"]
+		function.CallContext = o.withConversation(skill.Functions["summarize"], function.CallContext)
+		return
+	}
+}
+
+// RegisterWithStore returns a registration function for the chat skill that persists conversations in given store
+// (see WithStore)
+func RegisterWithStore(conversations store.ConversationStore) gosk.SkillRegistrationFunc {
+	return RegisterWith(WithStore(conversations))
+}
+
+// withConversation wraps the chat function call to load, summarize and store the input's conversation
+func (o *options) withConversation(summarizeFunction *gosk.Function, call func(ctx context.Context, input llm.Content) (llm.Content, error)) func(ctx context.Context, input llm.Content) (llm.Content, error) {
+	return func(ctx context.Context, input llm.Content) (llm.Content, error) {
+		var sessionID string
+		if o.conversations != nil {
+			sessionID = input.Property(SessionProperty).String()
+		}
+		// number of the conversation's contents that are already stored, the whole conversation is saved if none are
+		stored := 0
+		if sessionID != "" && input.Predecessor() == nil {
+			history, err := o.conversations.Load(sessionID)
+			if err != nil && !errors.Is(err, store.ErrSessionNotFound) {
+				return nil, err
+			}
+			if history != nil {
+				// the caller's input isn't modified
+				input = llm.WithoutPredecessor(input).WithPredecessor(history)
+				stored = len(llm.Chain(history))
+			}
+		}
+		summarized := false
+		if o.summary != nil && input.Predecessor() != nil {
+			var history llm.Content
+			var err error
+			if history, summarized, err = o.summary.summarize(ctx, summarizeFunction, input.Predecessor()); err != nil {
+				return nil, err
+			}
+			if summarized {
+				input = llm.WithoutPredecessor(input).WithPredecessor(history)
+			}
+		}
+		response, err := call(ctx, input)
+		if err != nil || sessionID == "" {
+			return response, err
+		}
+		if summarized || stored == 0 {
+			err = o.conversations.Save(sessionID, response)
+		} else {
+			contents := llm.Chain(response)
+			if stored > len(contents) {
+				stored = len(contents)
+			}
+			err = o.conversations.Append(sessionID, contents[stored:]...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+}
+
 func Register(generatorFactories llm.NewGeneratorFuncMap) (skill *gosk.Skill, err error) {
-	createChatFunction := func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error)) {
-		skillFunc = func(input llm.Content) (llm.Content, error) {
-			// add system at the beginning of the conversation (when there is no input's predecessor)
-			if input.Predecessor() == nil {
-				systemPrompt, err := llm.ExecuteTemplate(promptTemplate, input)
-				if err != nil {
-					return nil, err
-				}
-				systemInput := llm.NewContent(systemPrompt).SetRole(llm.RoleSystem)
-				input.WithPredecessor(systemInput)
-			}
-			response, err := generator.Generate(input)
-			return response.WithPredecessor(input), err
+	createChatFunction := func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(ctx context.Context, input llm.Content) (response llm.Content, err error)) {
+		// the system message (system.tmpl) is added at the beginning of the conversation by the semantic function
+		skillFunc = func(ctx context.Context, input llm.Content) (llm.Content, error) {
+			prompt, err := gosk.ExecuteTemplate(ctx, promptTemplate, input)
+			if err != nil {
+				return nil, err
+			}
+			response, err := llm.GenerateContext(ctx, generator, input.Set(prompt))
+			if err != nil {
+				return nil, err
+			}
+			if response.Predecessor() != nil {
+				// response already follows executed function calls
+				return response, nil
+			}
+			return response.WithPredecessor(input), nil
 		}
 		return
 	}
@@ -34,7 +137,7 @@ func Register(generatorFactories llm.NewGeneratorFuncMap) (skill *gosk.Skill, er
 	if err != nil {
 		return
 	}
-	skill, err = gosk.ParseSemanticSkillFromFS(subFS, generatorFactories, gosk.WithCustomCallForFunc("chatgpt", createChatFunction))
+	skill, err = gosk.ParseSemanticSkillFromFS(subFS, generatorFactories, gosk.WithCustomCallContextForFunc("chatgpt", createChatFunction))
 	if err != nil {
 		return
 	}
@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+const (
+	// SummaryName is the name of the system content that summarizes the earlier part of a conversation
+	SummaryName = "summary"
+	// summaryPrefix introduces the summary to the model
+	summaryPrefix = "Summary of the earlier conversation: "
+)
+
+// summaryOptions define when and how conversations are summarized
+type summaryOptions struct {
+	// threshold is the number of tokens of a conversation that triggers its summarization
+	threshold int
+	// keepLast is the number of latest contents that are kept as they are
+	keepLast int
+	// window counts the tokens of the conversation
+	window llm.ContextWindow
+}
+
+// WithSummary summarizes the oldest part of a conversation once it exceeds threshold tokens, keeping its first system
+// message and its keepLast latest contents. The summary is a system content named SummaryName, that is summarized again
+// with the next oldest part of the conversation once the threshold is exceeded again. The properties of the summarized
+// contents are kept with the summary. Tokens are counted with given tokenizer or approximated if it's nil.
+func WithSummary(threshold int, keepLast int, tokenizer llm.Tokenizer) Option {
+	return func(o *options) {
+		o.summary = &summaryOptions{
+			threshold: threshold,
+			keepLast:  keepLast,
+			window:    llm.ContextWindow{Tokenizer: tokenizer},
+		}
+	}
+}
+
+// summarize replaces the oldest part of the conversation with its summary if the conversation exceeds the threshold.
+// The conversation isn't modified, a summarized copy of it is returned.
+func (s *summaryOptions) summarize(ctx context.Context, summarizeFunction *gosk.Function, conversation llm.Content) (summarized llm.Content, ok bool, err error) {
+	if s.window.CountConversationTokens(conversation) <= s.threshold {
+		return conversation, false, nil
+	}
+	contents := llm.Chain(conversation)
+	start, end := 0, len(contents)-s.keepLast
+	if contents[0].Role() == llm.RoleSystem && contents[0].Name() != SummaryName {
+		// keep the initial system message
+		start = 1
+	}
+	if end-start <= 0 || end-start == 1 && contents[start].Name() == SummaryName {
+		// nothing (new) to summarize
+		return conversation, false, nil
+	}
+	if summarizeFunction == nil || summarizeFunction.CallContext == nil {
+		return nil, false, errors.New("missing summarize function")
+	}
+
+	var transcript strings.Builder
+	for _, content := range contents[start:end] {
+		role, text := string(content.Role()), content.String()
+		if content.Name() == SummaryName {
+			role, text = SummaryName, strings.TrimPrefix(text, summaryPrefix)
+		} else if role == "" {
+			role = string(llm.RoleUser)
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, text)
+	}
+	// the summary is neither streamed to the chat's client nor may it call the chat's functions
+	summaryCtx := llm.ContextWithFunctions(llm.ContextWithStream(ctx, nil), nil, nil)
+	response, err := summarizeFunction.CallContext(summaryCtx, llm.NewContent(transcript.String()))
+	if err != nil {
+		return nil, false, fmt.Errorf("summarizing conversation failed: %w", err)
+	}
+	summary := llm.NewContent(summaryPrefix + response.String()).SetRole(llm.RoleSystem).SetName(SummaryName)
+	// keep the properties of the summarized contents, e.g. the user's name
+	for path, property := range contents[end-1].Properties() {
+		switch path {
+		case "role", "name", "predecessor":
+			continue
+		}
+		summary.With(path, property.Value())
+	}
+
+	if start > 0 {
+		summary.WithPredecessor(llm.WithoutPredecessor(contents[0]))
+	}
+	summarized = summary
+	for _, content := range contents[end:] {
+		summarized = llm.WithoutPredecessor(content).WithPredecessor(summarized)
+	}
+	return summarized, true, nil
+}
@@ -1,6 +1,7 @@
 package planner
 
 import (
+	"context"
 	"embed"
 	"io/fs"
 	"text/template"
@@ -14,19 +15,26 @@ var fsAssets embed.FS
 
 func New(generatorFactories llm.NewGeneratorFuncMap) (skill *gosk.Skill, err error) {
 
-	createChatFunction := func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error)) {
-		skillFunc = func(input llm.Content) (llm.Content, error) {
+	createChatFunction := func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(ctx context.Context, input llm.Content) (response llm.Content, err error)) {
+		skillFunc = func(ctx context.Context, input llm.Content) (llm.Content, error) {
 			// add system prompt to input if not already present
 			if input.Predecessor() == nil {
-				systemPrompt, err := llm.ExecuteTemplate(promptTemplate, input)
+				systemPrompt, err := gosk.ExecuteTemplate(ctx, promptTemplate, input)
 				if err != nil {
 					return nil, err
 				}
 				systemInput := llm.NewContent(systemPrompt).SetRole(llm.RoleSystem)
 				input.WithPredecessor(systemInput)
 			}
-			response, err := generator.Generate(input)
-			return response.WithPredecessor(input), err
+			response, err := llm.GenerateContext(ctx, generator, input)
+			if err != nil {
+				return nil, err
+			}
+			if response.Predecessor() != nil {
+				// response already follows executed function calls
+				return response, nil
+			}
+			return response.WithPredecessor(input), nil
 		}
 		return
 	}
@@ -35,7 +43,7 @@ func New(generatorFactories llm.NewGeneratorFuncMap) (skill *gosk.Skill, err err
 	if err != nil {
 		return
 	}
-	skill, err = gosk.ParseSemanticSkillFromFS(subFS, generatorFactories, gosk.WithCustomCallForFunc("chatgpt", createChatFunction))
+	skill, err = gosk.ParseSemanticSkillFromFS(subFS, generatorFactories, gosk.WithCustomCallContextForFunc("chatgpt", createChatFunction))
 	if err != nil {
 		return
 	}
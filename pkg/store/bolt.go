@@ -0,0 +1,176 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+	"go.etcd.io/bbolt"
+)
+
+// sessionsBucket is the root bucket with a nested bucket per session
+var sessionsBucket = []byte("sessions")
+
+// BoltStore stores sessions in an embedded bbolt key-value database. Each session is a bucket with the session's
+// contents as values of sequential keys.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+// OpenBoltStore opens the database file at path, which is created if it doesn't exist.
+// The file is locked while the store is open, so it must be closed.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening store `%s` failed: %w", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// sequenceKey returns the key of a session's content with given sequence number
+func sequenceKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
+
+// appendContents puts the contents with the session bucket's next sequence numbers
+func appendContents(session *bbolt.Bucket, contents []llm.Content) error {
+	for _, content := range contents {
+		data, err := marshalContent(content)
+		if err != nil {
+			return err
+		}
+		sequence, err := session.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err = session.Put(sequenceKey(sequence), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load returns the session's conversation
+func (s *BoltStore) Load(sessionID string) (conversation llm.Content, err error) {
+	if err = validateSessionID(sessionID); err != nil {
+		return
+	}
+	var contents [][]byte
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		session := tx.Bucket(sessionsBucket).Bucket([]byte(sessionID))
+		if session == nil {
+			return fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+		}
+		// values are only valid during the transaction
+		return session.ForEach(func(_, value []byte) error {
+			contents = append(contents, append([]byte(nil), value...))
+			return nil
+		})
+	})
+	if err != nil {
+		return
+	}
+	if conversation, err = unmarshalConversation(contents); err != nil {
+		err = fmt.Errorf("loading session `%s` failed: %w", sessionID, err)
+	}
+	return
+}
+
+// Append appends the contents to the session's bucket
+func (s *BoltStore) Append(sessionID string, contents ...llm.Content) error {
+	if err := validateSessionID(sessionID); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		session, err := tx.Bucket(sessionsBucket).CreateBucketIfNotExists([]byte(sessionID))
+		if err != nil {
+			return err
+		}
+		return appendContents(session, contents)
+	})
+}
+
+// Save replaces the session's bucket
+func (s *BoltStore) Save(sessionID string, conversation llm.Content) error {
+	if err := validateSessionID(sessionID); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		if err := sessions.DeleteBucket([]byte(sessionID)); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+			return err
+		}
+		session, err := sessions.CreateBucket([]byte(sessionID))
+		if err != nil {
+			return err
+		}
+		return appendContents(session, llm.Chain(conversation))
+	})
+}
+
+// List returns the IDs of all sessions, which bbolt keeps in lexical order
+func (s *BoltStore) List() (sessionIDs []string, err error) {
+	sessionIDs = []string{}
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(key, _ []byte) error {
+			sessionIDs = append(sessionIDs, string(key))
+			return nil
+		})
+	})
+	return
+}
+
+// Fork copies the session's bucket
+func (s *BoltStore) Fork(sessionID string, newSessionID string) error {
+	if err := errors.Join(validateSessionID(sessionID), validateSessionID(newSessionID)); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		session := sessions.Bucket([]byte(sessionID))
+		if session == nil {
+			return fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+		}
+		newSession, err := sessions.CreateBucket([]byte(newSessionID))
+		if errors.Is(err, bbolt.ErrBucketExists) {
+			return fmt.Errorf("%w: `%s`", ErrSessionExists, newSessionID)
+		}
+		if err != nil {
+			return err
+		}
+		if err = session.ForEach(newSession.Put); err != nil {
+			return err
+		}
+		return newSession.SetSequence(session.Sequence())
+	})
+}
+
+// Delete deletes the session's bucket
+func (s *BoltStore) Delete(sessionID string) error {
+	if err := validateSessionID(sessionID); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(sessionsBucket).DeleteBucket([]byte(sessionID))
+		if errors.Is(err, bbolt.ErrBucketNotFound) {
+			return fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+		}
+		return err
+	})
+}
+
+// Close closes the database and releases its file lock
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// FileExtension is the extension of a FileStore's session files
+const FileExtension = ".jsonl"
+
+// FileStore stores each session in a JSONL file of its directory with one content per line
+type FileStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// NewFileStore creates a file store with its sessions in given directory, which is created if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating store directory `%s` failed: %w", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// file returns the path of the session's file
+func (s *FileStore) file(sessionID string) (string, error) {
+	if err := validateSessionID(sessionID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, sessionID+FileExtension), nil
+}
+
+// Load returns the session's conversation
+func (s *FileStore) Load(sessionID string) (conversation llm.Content, err error) {
+	file, err := s.file(sessionID)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	data, err := os.ReadFile(file)
+	s.mutex.Unlock()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+	}
+	if err != nil {
+		return
+	}
+	var contents [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			contents = append(contents, scanner.Bytes())
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if conversation, err = unmarshalConversation(contents); err != nil {
+		err = fmt.Errorf("loading session `%s` failed: %w", sessionID, err)
+	}
+	return
+}
+
+// lines marshals the contents with one content per line
+func lines(contents []llm.Content) ([]byte, error) {
+	var buffer bytes.Buffer
+	for _, content := range contents {
+		data, err := marshalContent(content)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes(), nil
+}
+
+// Append appends the contents as lines to the session's file
+func (s *FileStore) Append(sessionID string, contents ...llm.Content) (err error) {
+	file, err := s.file(sessionID)
+	if err != nil {
+		return
+	}
+	data, err := lines(contents)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	return errors.Join(err, f.Close())
+}
+
+// Save replaces the session's file
+func (s *FileStore) Save(sessionID string, conversation llm.Content) (err error) {
+	file, err := s.file(sessionID)
+	if err != nil {
+		return
+	}
+	data, err := lines(llm.Chain(conversation))
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeFile(file, data)
+}
+
+// writeFile writes the file atomically by renaming a temporary file
+func writeFile(file string, data []byte) error {
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+// List returns the IDs of all sessions in the store's directory
+func (s *FileStore) List() (sessionIDs []string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	sessionIDs = []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), FileExtension) {
+			continue
+		}
+		sessionIDs = append(sessionIDs, strings.TrimSuffix(entry.Name(), FileExtension))
+	}
+	return
+}
+
+// Fork copies the session's file
+func (s *FileStore) Fork(sessionID string, newSessionID string) (err error) {
+	file, err := s.file(sessionID)
+	if err != nil {
+		return
+	}
+	newFile, err := s.file(newSessionID)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+	}
+	if err != nil {
+		return
+	}
+	if _, err = os.Stat(newFile); err == nil {
+		return fmt.Errorf("%w: `%s`", ErrSessionExists, newSessionID)
+	}
+	return writeFile(newFile, data)
+}
+
+// Delete removes the session's file
+func (s *FileStore) Delete(sessionID string) (err error) {
+	file, err := s.file(sessionID)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err = os.Remove(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: `%s`", ErrSessionNotFound, sessionID)
+	}
+	return
+}
+
+// Close does nothing, since session files are only opened while they are accessed
+func (s *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+// Package store persists chat conversations, i.e. chains of llm.Content, by session ID
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+var (
+	// ErrSessionNotFound is returned when a session doesn't exist in the store
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExists is returned when a session is forked to a session that already exists
+	ErrSessionExists = errors.New("session already exists")
+	// ErrInvalidSessionID is returned for empty session IDs or session IDs with path separators
+	ErrInvalidSessionID = errors.New("invalid session id")
+)
+
+// ConversationStore stores conversations by session ID. A conversation is stored as its contents (turns) in chronological
+// order and is loaded as its last content with the preceding contents as predecessors.
+type ConversationStore interface {
+	// Load returns the session's conversation, i.e. its last content with all preceding contents as predecessors.
+	// ErrSessionNotFound is returned if the session doesn't exist, nil if the session exists without contents.
+	Load(sessionID string) (conversation llm.Content, err error)
+	// Append appends given contents to the session in given order and creates the session if it doesn't exist.
+	// The contents' predecessors are not appended.
+	Append(sessionID string, contents ...llm.Content) error
+	// Save replaces the session's conversation with given content and its predecessors
+	Save(sessionID string, conversation llm.Content) error
+	// List returns the IDs of all sessions in lexical order
+	List() (sessionIDs []string, err error)
+	// Fork copies the session's conversation to a new session, that can be continued independently
+	Fork(sessionID string, newSessionID string) error
+	// Delete deletes the session
+	Delete(sessionID string) error
+	// Close closes the store
+	Close() error
+}
+
+// validateSessionID checks that the session ID can be used as file name
+func validateSessionID(sessionID string) error {
+	if sessionID == "" || sessionID == "." || sessionID == ".." || strings.ContainsAny(sessionID, `/\`) {
+		return fmt.Errorf("%w: `%s`", ErrInvalidSessionID, sessionID)
+	}
+	return nil
+}
+
+// marshalContent marshals the content without its predecessor
+func marshalContent(content llm.Content) ([]byte, error) {
+	return json.Marshal(llm.WithoutPredecessor(content))
+}
+
+// unmarshalConversation unmarshals the contents and chains them in given order
+func unmarshalConversation(contents [][]byte) (conversation llm.Content, err error) {
+	for i, data := range contents {
+		var content llm.Content
+		if content, err = llm.UnmarshalContent(data); err != nil {
+			return nil, fmt.Errorf("unmarshalling content %d failed: %w", i+1, err)
+		}
+		if conversation != nil {
+			content.WithPredecessor(conversation)
+		}
+		conversation = content
+	}
+	return
+}
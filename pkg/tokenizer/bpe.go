@@ -0,0 +1,127 @@
+// Package tokenizer provides an offline byte pair encoding (BPE) tokenizer for OpenAI's models
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitPattern splits texts into pieces like the `cl100k_base` encoding of OpenAI's GPT-3.5 and GPT-4 models. The
+// original pattern's `\s+(?!\S)` lookahead isn't supported by Go's regexp package and is emulated while splitting.
+var splitPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// BPE tokenizes texts with the ranks of a tiktoken encoding, e.g. `cl100k_base.tiktoken`
+type BPE struct {
+	ranks map[string]int
+}
+
+// ReadBPE reads the ranks of a tiktoken encoding, i.e. lines of base64 encoded tokens with their rank
+func ReadBPE(r io.Reader) (*BPE, error) {
+	bpe := &BPE{ranks: map[string]int{}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid rank at line %d", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token at line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank at line %d: %w", line, err)
+		}
+		bpe.ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// every text can be encoded if all single bytes are tokens
+	for b := 0; b < 256; b++ {
+		if _, ok := bpe.ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("missing token for byte %#x", b)
+		}
+	}
+	return bpe, nil
+}
+
+// Encode returns the tokens of the text
+func (b *BPE) Encode(text string) (tokens []int) {
+	for _, piece := range split(text) {
+		tokens = append(tokens, b.encodePiece([]byte(piece))...)
+	}
+	return
+}
+
+// CountTokens returns the number of tokens of the text
+func (b *BPE) CountTokens(text string) int {
+	return len(b.Encode(text))
+}
+
+// encodePiece merges the adjacent parts of the piece with the lowest rank until no more parts can be merged
+func (b *BPE) encodePiece(piece []byte) []int {
+	if rank, ok := b.ranks[string(piece)]; ok {
+		return []int{rank}
+	}
+	// boundaries of the piece's parts, initially single bytes
+	boundaries := make([]int, len(piece)+1)
+	for i := range boundaries {
+		boundaries[i] = i
+	}
+	for {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i < len(boundaries)-2; i++ {
+			if rank, ok := b.ranks[string(piece[boundaries[i]:boundaries[i+2]])]; ok && rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		boundaries = append(boundaries[:minIndex+1], boundaries[minIndex+2:]...)
+	}
+	tokens := make([]int, 0, len(boundaries)-1)
+	for i := 0; i < len(boundaries)-1; i++ {
+		tokens = append(tokens, b.ranks[string(piece[boundaries[i]:boundaries[i+1]])])
+	}
+	return tokens
+}
+
+// split splits the text into pieces that are encoded separately
+func split(text string) (pieces []string) {
+	for len(text) > 0 {
+		loc := splitPattern.FindStringIndex(text)
+		if loc == nil {
+			pieces = append(pieces, text)
+			break
+		}
+		piece := text[loc[0]:loc[1]]
+		// emulate `\s+(?!\S)`: whitespace that is followed by text leaves its last character to the following piece
+		if loc[1] < len(text) && isSpace(piece) && utf8.RuneCountInString(piece) > 1 {
+			last, _ := utf8.DecodeLastRuneInString(piece)
+			if last != '\r' && last != '\n' {
+				piece = piece[:len(piece)-utf8.RuneLen(last)]
+			}
+		}
+		pieces = append(pieces, piece)
+		text = text[loc[0]+len(piece):]
+	}
+	return
+}
+
+// isSpace returns whether the text consists of whitespace only
+func isSpace(text string) bool {
+	return strings.TrimFunc(text, unicode.IsSpace) == ""
+}
@@ -0,0 +1,128 @@
+package gosk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// skillWatcher watches a skill directory for changes
+type skillWatcher struct {
+	fsys fs.FS
+	// dir of the skill in fsys
+	dir string
+	// name the skill has been added with to the kernel, empty if it couldn't be added
+	name string
+	// skill is the version of the skill that has been added to the kernel, nil if it couldn't be added
+	skill *Skill
+	// fingerprint of the skill's files
+	fingerprint string
+}
+
+// LoadSkillsFromDir loads all skills found in the directory tree (see LoadSkillsFromFS). If the kernel has been created with
+// WithHotReload, the skills' directories are watched and changed skills are parsed again and replaced in the kernel.
+// Calls that are in flight while a skill is replaced finish with the old version of the skill. Skills that are removed
+// (RemoveSkill) or replaced (ReplaceSkill) aren't reloaded anymore.
+func (sk *SemanticKernel) LoadSkillsFromDir(dir string) (err error) {
+	watchers, err := sk.loadSkills(os.DirFS(dir))
+	if sk.options.hotReloadInterval <= 0 || len(watchers) <= 0 {
+		return
+	}
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if sk.closed {
+		// skills of a closed kernel aren't watched
+		return
+	}
+	sk.watching.Add(1)
+	go sk.watch(watchers)
+	return
+}
+
+// Close stops watching skill directories for hot reloading. It can be called repeatedly and concurrently.
+func (sk *SemanticKernel) Close() error {
+	sk.mutex.Lock()
+	if !sk.closed {
+		sk.closed = true
+		close(sk.stopWatching)
+	}
+	sk.mutex.Unlock()
+	sk.watching.Wait()
+	return nil
+}
+
+// watch checks the watchers' skill directories for changes until the kernel is closed
+func (sk *SemanticKernel) watch(watchers []*skillWatcher) {
+	defer sk.watching.Done()
+	ticker := time.NewTicker(sk.options.hotReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sk.stopWatching:
+			return
+		case <-ticker.C:
+			for _, watcher := range watchers {
+				fingerprint := skillFingerprint(watcher.fsys, watcher.dir)
+				if fingerprint == watcher.fingerprint {
+					continue
+				}
+				watcher.fingerprint = fingerprint
+				reloaded, err := sk.reloadSkill(watcher)
+				if err != nil {
+					sk.options.logger.Printf("reloading skill `%s` failed, keeping current version: %v", watcher.dir, err)
+					continue
+				}
+				if !reloaded {
+					sk.options.logger.Printf("skill `%s` has been removed or replaced, its changes aren't reloaded", watcher.name)
+					continue
+				}
+				sk.options.logger.Printf("skill `%s` reloaded", watcher.name)
+			}
+		}
+	}
+}
+
+// reloadSkill parses the watcher's skill again and replaces it in the kernel. Skills that have been removed from or
+// replaced in the kernel since they have been loaded aren't reloaded.
+func (sk *SemanticKernel) reloadSkill(watcher *skillWatcher) (reloaded bool, err error) {
+	skill, err := parseSemanticSkillInDir(watcher.fsys, watcher.dir, sk.generatorFactories())
+	if err != nil {
+		return false, err
+	}
+	sanitizeSkill(skill.Name, skill)
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if watcher.skill != nil && sk.skills[watcher.name] != watcher.skill {
+		// removed or replaced
+		return false, nil
+	}
+	if skill.Name != watcher.name {
+		if _, exists := sk.skills[skill.Name]; exists {
+			return false, fmt.Errorf("skill `%s` already added", skill.Name)
+		}
+		delete(sk.skills, watcher.name)
+	}
+	sk.skills[skill.Name] = skill
+	watcher.name, watcher.skill = skill.Name, skill
+	return true, nil
+}
+
+// skillFingerprint returns a fingerprint of the modification times and sizes of all files of a skill, e.g. its configs,
+// templates and examples
+func skillFingerprint(fsys fs.FS, dir string) string {
+	var fingerprint strings.Builder
+	fs.WalkDir(fsys, dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", filePath, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return fingerprint.String()
+}
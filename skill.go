@@ -2,9 +2,11 @@ package gosk
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
+	"path"
+	"text/template"
 
 	"github.com/mfmayer/gosk/pkg/llm"
 )
@@ -63,23 +65,27 @@ func (s *Skill) String() string {
 type skillConfig struct {
 	*Skill
 	GeneratorConfigs map[string]llm.GeneratorConfig `json:"generators,omitempty"`
+	// Templates is the skill's directory with partial templates (`*.tmpl`) that are shared by its functions
+	Templates string `json:"templates,omitempty"`
 }
 
 // ParseSemanticSkillFromFS parses a skill from fsys file system (see assets/skills for examples).
 // Given generatorFactories are used to create and return generators that are configured for this skill.
+// The skill's config is read from `config.json` or `config.yaml`. Alternatively a single `skill.yaml` can hold the skill's
+// config together with its functions, whose configs contain their `prompt` (and optional `system`) template.
 func ParseSemanticSkillFromFS(fsys fs.FS, generatorFactories llm.NewGeneratorFuncMap, options ...createSemanticFunctionsOption) (skill *Skill, err error) {
-	// open config file
-	file, err := fsys.Open("config.json")
-	if err != nil {
-		err = fmt.Errorf("opening `config.json` failed: %w", err)
-		return
-	}
-	defer file.Close()
-
 	// read config file
-	data, err := io.ReadAll(file)
+	data, configFile, err := readConfig(fsys, ".", "config")
+	var inlineFunctions bool
+	if errors.Is(err, fs.ErrNotExist) {
+		// read skill file with inline functions instead
+		var skillErr error
+		if data, configFile, skillErr = readConfig(fsys, ".", "skill"); !errors.Is(skillErr, fs.ErrNotExist) {
+			err = skillErr
+			inlineFunctions = true
+		}
+	}
 	if err != nil {
-		err = fmt.Errorf("reading `config.json` failed: %w", err)
 		return
 	}
 
@@ -87,14 +93,24 @@ func ParseSemanticSkillFromFS(fsys fs.FS, generatorFactories llm.NewGeneratorFun
 	var skillConfig skillConfig
 	err = json.Unmarshal(data, &skillConfig)
 	if err != nil {
-		err = fmt.Errorf("unmarshalling `config.json` failed: %w", err)
+		err = fmt.Errorf("unmarshalling `%s` failed: %w", configFile, err)
 		return
 	}
 	skill = skillConfig.Skill
 	if skill == nil {
-		err = fmt.Errorf("invalid skill `config.json`")
+		err = fmt.Errorf("invalid skill `%s`", configFile)
 		return
 	}
+	if inlineFunctions {
+		var functionConfigs struct {
+			Functions map[string]map[string]interface{} `json:"functions"`
+		}
+		if err = json.Unmarshal(data, &functionConfigs); err != nil {
+			err = fmt.Errorf("unmarshalling functions of `%s` failed: %w", configFile, err)
+			return
+		}
+		options = append(options, withInlineFunctions(configFile, functionConfigs.Functions))
+	}
 
 	// create response generators
 	generators, err := generatorFactories.CreateGenerators(skillConfig.GeneratorConfigs)
@@ -103,6 +119,16 @@ func ParseSemanticSkillFromFS(fsys fs.FS, generatorFactories llm.NewGeneratorFun
 		return
 	}
 
+	// parse shared partial templates
+	if skillConfig.Templates != "" {
+		var partials *template.Template
+		if partials, err = sharedTemplatesFromFS(fsys, skillConfig.Templates); err != nil {
+			err = fmt.Errorf("parsing templates `%s` failed: %w", skillConfig.Templates, err)
+			return
+		}
+		options = append([]createSemanticFunctionsOption{withPartialsForFuncs(partials)}, options...)
+	}
+
 	// create configured skill functions
 	functions, err := ParseSemanticFunctionsFromFS(fsys, generators, options...)
 	if err != nil {
@@ -114,3 +140,108 @@ func ParseSemanticSkillFromFS(fsys fs.FS, generatorFactories llm.NewGeneratorFun
 
 	return
 }
+
+// sharedTemplatesFromFS parses the `*.tmpl` files in given directory of fsys
+func sharedTemplatesFromFS(fsys fs.FS, dir string) (*template.Template, error) {
+	templatesFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return llm.TemplatesFromFS(templatesFS, "*.tmpl")
+}
+
+// SkillError is returned for a skill that couldn't be parsed while parsing multiple skills
+type SkillError struct {
+	// Path of the skill's directory
+	Path string
+	// Err why the skill couldn't be parsed
+	Err error
+}
+
+func (e *SkillError) Error() string {
+	return fmt.Sprintf("parsing skill `%s` failed: %v", e.Path, e.Err)
+}
+
+func (e *SkillError) Unwrap() error {
+	return e.Err
+}
+
+// isSkillDir checks whether the directory contains a skill's (and not a function's) config or a skill file
+func isSkillDir(fsys fs.FS, dir string) bool {
+	data, _, err := readConfig(fsys, dir, "config")
+	if errors.Is(err, fs.ErrNotExist) {
+		_, _, err = readConfig(fsys, dir, "skill")
+		return !errors.Is(err, fs.ErrNotExist)
+	}
+	if err != nil {
+		// invalid configs are reported while parsing
+		return true
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		// invalid configs are reported while parsing
+		return true
+	}
+	// function configs reference their generator
+	_, isFunction := config["generator"]
+	return !isFunction
+}
+
+// findSkillDirs walks the fsys file system and returns all directories with a skill config or skill file.
+// Sub directories of skill directories are not walked, since they contain the skill's functions.
+func findSkillDirs(fsys fs.FS) (dirs []string, err error) {
+	walkErr := fs.WalkDir(fsys, ".", func(dir string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			err = errors.Join(err, &SkillError{Path: dir, Err: walkErr})
+			return nil
+		}
+		if !d.IsDir() || !isSkillDir(fsys, dir) {
+			return nil
+		}
+		dirs = append(dirs, dir)
+		return fs.SkipDir
+	})
+	err = errors.Join(err, walkErr)
+	return
+}
+
+// ParseSemanticSkillsFromFS walks the fsys file system and parses every skill that is found in it. A skill is found in
+// every directory with a skill config (or `skill.yaml`), its sub directories and `*.prompt` files are parsed as the skill's
+// functions. Skills without a name are named by their directory. Skills that can't be parsed are reported with a SkillError
+// without aborting the others.
+func ParseSemanticSkillsFromFS(fsys fs.FS, generatorFactories llm.NewGeneratorFuncMap, options ...createSemanticFunctionsOption) (skills map[string]*Skill, err error) {
+	skills = map[string]*Skill{}
+	skillDirs, err := findSkillDirs(fsys)
+	for _, dir := range skillDirs {
+		skill, parseErr := parseSemanticSkillInDir(fsys, dir, generatorFactories, options...)
+		if parseErr != nil {
+			err = errors.Join(err, &SkillError{Path: dir, Err: parseErr})
+			continue
+		}
+		if _, exists := skills[skill.Name]; exists {
+			err = errors.Join(err, &SkillError{Path: dir, Err: fmt.Errorf("skill `%s` already exists", skill.Name)})
+			continue
+		}
+		skills[skill.Name] = skill
+	}
+	return
+}
+
+// parseSemanticSkillInDir parses the skill in given directory of fsys and names it by its directory if it has no name
+func parseSemanticSkillInDir(fsys fs.FS, dir string, generatorFactories llm.NewGeneratorFuncMap, options ...createSemanticFunctionsOption) (skill *Skill, err error) {
+	subFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return
+	}
+	skill, err = ParseSemanticSkillFromFS(subFS, generatorFactories, options...)
+	if err != nil {
+		return nil, err
+	}
+	if skill.Name == "" {
+		skill.Name = path.Base(dir)
+	}
+	if skill.Name == "." {
+		return nil, fmt.Errorf("skill has no name")
+	}
+	return
+}
@@ -1,12 +1,13 @@
 package gosk
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
+	"path"
+	"regexp"
 	"strings"
 	"text/template"
 
@@ -25,7 +26,7 @@ const (
 	TypeNull    Type = "null"
 )
 
-// Parameter defines a function's parameter with its name, description and type
+// Parameter defines a function's parameter with its name, description, type and constraints
 type Parameter struct {
 	Name        string      `json:"name,omitempty"`
 	Description string      `json:"description"`
@@ -33,7 +34,35 @@ type Parameter struct {
 	Enum        []string    `json:"enum,omitempty"`
 	Required    bool        `json:"required,omitempty"`
 	Default     interface{} `json:"default,omitempty"`
-	//TODO: Add additional potentially valuable definitions like min, max, etc
+	// Minimum and Maximum constrain numeric values (inclusive)
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	// MinLength and MaxLength constrain the number of characters of string values
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+	// Pattern is a regular expression that string values must match
+	Pattern string `json:"pattern,omitempty"`
+	// Items defines the items of array values
+	Items *Parameter `json:"items,omitempty"`
+	// pattern is the compiled Pattern
+	pattern *regexp.Regexp
+}
+
+type parameterAlias Parameter
+
+// UnmarshalJSON unmarshals the parameter and compiles its pattern, so that invalid patterns are reported when the
+// function's config is loaded
+func (p *Parameter) UnmarshalJSON(data []byte) (err error) {
+	if err = json.Unmarshal(data, (*parameterAlias)(p)); err != nil {
+		return
+	}
+	p.pattern = nil
+	if p.Pattern != "" {
+		if p.pattern, err = regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern `%s`: %w", p.Pattern, err)
+		}
+	}
+	return
 }
 
 // Function defines and describes a skill's function with its input properties and its actual function call
@@ -46,38 +75,132 @@ type Function struct {
 	Plannable bool `json:"plannable,omitempty"`
 	// InputProperties map whose keys are the input property names and whose values are the input property definitions
 	InputProperties map[string]*Parameter `json:"inputProperties"`
-	// call holds the function that is executed when the skill function is called
+	// OutputProperties map whose keys are the output property names and whose values are the output property definitions.
+	// If set, the text generated by a semantic function is parsed as JSON object into these properties.
+	OutputProperties map[string]*Parameter `json:"outputProperties,omitempty"`
+	// RepairAttempts is the number of times the generator is asked to correct output that doesn't conform to the output properties
+	RepairAttempts int `json:"repairAttempts,omitempty"`
+	// Call holds the function that is executed when the skill function is called
 	Call func(input llm.Content) (output llm.Content, err error) `json:"-"`
+	// CallContext holds the context aware function that is executed when the skill function is called.
+	// It is preferred by the semantic kernel over Call if set.
+	CallContext func(ctx context.Context, input llm.Content) (output llm.Content, err error) `json:"-"`
+}
+
+// callFunction calls the function with given context, preferably with its CallContext
+func callFunction(ctx context.Context, function *Function, input llm.Content) (output llm.Content, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if function.CallContext != nil {
+		return function.CallContext(ctx, input)
+	}
+	if function.Call != nil {
+		return function.Call(input)
+	}
+	err = fmt.Errorf("function `%s` is not callable", function.Name)
+	return
+}
+
+// CreateSemanticFunctionCallContext creates a context aware function call from a prompt template and a generator
+type CreateSemanticFunctionCallContext func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(ctx context.Context, input llm.Content) (response llm.Content, err error))
+
+// withContext turns a semantic function call creator into a context aware one that checks the context before calling
+func withContext(createSemanticFunctionCall func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error))) CreateSemanticFunctionCallContext {
+	return func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(ctx context.Context, input llm.Content) (response llm.Content, err error)) {
+		call := createSemanticFunctionCall(promptTemplate, generator)
+		if call == nil {
+			return
+		}
+		skillFunc = func(ctx context.Context, input llm.Content) (response llm.Content, err error) {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			return call(input)
+		}
+		return
+	}
 }
 
 // functionConfig is used to unmarshal function configuration into it
 type functionConfig struct {
 	*Function
 	Generator string `json:"generator"`
+	// Examples configures few-shot examples, "examples.jsonl" is used with all its examples if not set
+	Examples *ExamplesConfig `json:"examples,omitempty"`
 }
 
 type createSemanticFunctionsOptionProperties struct {
-	createSemanticFunctions map[string]func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error))
+	createSemanticFunctions map[string]CreateSemanticFunctionCallContext
+	partials                *template.Template
+	inlineFunctions         map[string]map[string]interface{}
+	inlineSource            string
 }
 
 type createSemanticFunctionsOption func(properties *createSemanticFunctionsOptionProperties)
 
 // WithCustomCallForFunc allows to create selectively custom semantic function calls while parsing multiple semantic functions with ParseSemanticFunctionsFromFS
 func WithCustomCallForFunc(funcName string, createSemanticFunctionCall func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error))) (option createSemanticFunctionsOption) {
+	option = func(properties *createSemanticFunctionsOptionProperties) {
+		properties.createSemanticFunctions[funcName] = withContext(createSemanticFunctionCall)
+	}
+	return
+}
+
+// withPartialsForFuncs adds shared partial templates to the templates of all semantic functions
+func withPartialsForFuncs(partials *template.Template) (option createSemanticFunctionsOption) {
+	option = func(properties *createSemanticFunctionsOptionProperties) {
+		properties.partials = partials
+	}
+	return
+}
+
+// withInlineFunctions adds functions whose configs contain their prompts, e.g. from a skill's `skill.yaml`
+func withInlineFunctions(source string, configs map[string]map[string]interface{}) (option createSemanticFunctionsOption) {
+	option = func(properties *createSemanticFunctionsOptionProperties) {
+		properties.inlineSource = source
+		properties.inlineFunctions = configs
+	}
+	return
+}
+
+// parseOptions returns the options to parse the function with given name
+func (properties *createSemanticFunctionsOptionProperties) parseOptions(functionName string) []parseSemanticFunctionFromFSOption {
+	parseOptions := []parseSemanticFunctionFromFSOption{withPartials(properties.partials)}
+	// check for custom option
+	if custemCreateSemanticFunctionCall, ok := properties.createSemanticFunctions[functionName]; ok {
+		// create function with given option
+		parseOptions = append(parseOptions, WithCustomCallContext(custemCreateSemanticFunctionCall))
+	}
+	return parseOptions
+}
+
+// WithCustomCallContextForFunc allows to create selectively custom context aware semantic function calls while parsing multiple semantic functions with ParseSemanticFunctionsFromFS
+func WithCustomCallContextForFunc(funcName string, createSemanticFunctionCall CreateSemanticFunctionCallContext) (option createSemanticFunctionsOption) {
 	option = func(properties *createSemanticFunctionsOptionProperties) {
 		properties.createSemanticFunctions[funcName] = createSemanticFunctionCall
 	}
 	return
 }
 
+// ParseSemanticFunctionsFromFS parses the functions in the sub directories of fsys (see ParseSemanticFunctionFromFS) and in its
+// single-file function definitions `*.prompt` (see ParseSemanticFunctionFile). Functions are named by their lower cased directory
+// or file name.
 func ParseSemanticFunctionsFromFS(fsys fs.FS, generators map[string]llm.Generator, options ...createSemanticFunctionsOption) (functions map[string]*Function, err error) {
 	optionProperties := createSemanticFunctionsOptionProperties{
-		createSemanticFunctions: map[string]func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error)){},
+		createSemanticFunctions: map[string]CreateSemanticFunctionCallContext{},
 	}
 	for _, option := range options {
 		option(&optionProperties)
 	}
 	functions = map[string]*Function{}
+	addFunction := func(functionName string, function *Function, source string) {
+		if _, exists := functions[functionName]; exists {
+			err = errors.Join(err, fmt.Errorf("function `%s` of `%s` is already defined", functionName, source))
+			return
+		}
+		functions[functionName] = function
+	}
 	// find and parse skill functions in sub directories
 	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
@@ -86,6 +209,17 @@ func ParseSemanticFunctionsFromFS(fsys fs.FS, generators map[string]llm.Generato
 	}
 	for _, d := range entries {
 		if !d.IsDir() {
+			// parse single-file functions
+			if path.Ext(d.Name()) != PromptFileExtension {
+				continue
+			}
+			functionName := strings.ToLower(strings.TrimSuffix(d.Name(), PromptFileExtension))
+			function, parseFunctionErr := ParseSemanticFunctionFile(fsys, d.Name(), generators, optionProperties.parseOptions(functionName)...)
+			if parseFunctionErr != nil {
+				err = errors.Join(err, parseFunctionErr)
+				continue
+			}
+			addFunction(functionName, function, d.Name())
 			continue
 		}
 		// create subFS for subdirectory
@@ -96,16 +230,7 @@ func ParseSemanticFunctionsFromFS(fsys fs.FS, generators map[string]llm.Generato
 		}
 		// functionName
 		functionName := strings.ToLower(d.Name())
-		var function *Function
-		var parseFunctionErr error
-		// check for custom option
-		if custemCreateSemanticFunctionCall, ok := optionProperties.createSemanticFunctions[functionName]; ok {
-			// create function with given option
-			function, parseFunctionErr = ParseSemanticFunctionFromFS(subFS, generators, WithCustomCall(custemCreateSemanticFunctionCall))
-		} else {
-			// create default function
-			function, parseFunctionErr = ParseSemanticFunctionFromFS(subFS, generators)
-		}
+		function, parseFunctionErr := ParseSemanticFunctionFromFS(subFS, generators, optionProperties.parseOptions(functionName)...)
 		if parseFunctionErr != nil {
 			if !errors.Is(parseFunctionErr, fs.ErrNotExist) {
 				// if function config file doesn't exist ignore the error and
@@ -116,13 +241,24 @@ func ParseSemanticFunctionsFromFS(fsys fs.FS, generators map[string]llm.Generato
 			continue
 		}
 		// add function to skill with its directory name as key
-		functions[functionName] = function
+		addFunction(functionName, function, d.Name())
+	}
+	// parse inline functions
+	for name, config := range optionProperties.inlineFunctions {
+		functionName := strings.ToLower(name)
+		function, parseFunctionErr := parseInlineSemanticFunction(fsys, optionProperties.inlineSource, config, generators, optionProperties.parseOptions(functionName)...)
+		if parseFunctionErr != nil {
+			err = errors.Join(err, fmt.Errorf("parsing function `%s` of `%s` failed: %w", name, optionProperties.inlineSource, parseFunctionErr))
+			continue
+		}
+		addFunction(functionName, function, optionProperties.inlineSource)
 	}
 	return
 }
 
 type parseSemanticFunctionFromFSOptionProperties struct {
-	createSemanticFunction func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error))
+	createSemanticFunction CreateSemanticFunctionCallContext
+	partials               *template.Template
 }
 
 type parseSemanticFunctionFromFSOption func(properties *parseSemanticFunctionFromFSOptionProperties)
@@ -130,41 +266,62 @@ type parseSemanticFunctionFromFSOption func(properties *parseSemanticFunctionFro
 // WithCustomCall allows to create a custom semantic function call while parsing a semantic function with ParseSemanticFunctionFromFS
 func WithCustomCall(createSemanticFunctionCall func(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error))) (option parseSemanticFunctionFromFSOption) {
 	option = func(properties *parseSemanticFunctionFromFSOptionProperties) {
-		properties.createSemanticFunction = createSemanticFunctionCall
+		properties.createSemanticFunction = withContext(createSemanticFunctionCall)
 	}
 	return
 }
 
-// ParseFunctionFromFS finds "config.json" with function comfiguration.
-// Prompt templates will be created from "*.tmpl" files with at least "skprompt.tmpl" is needed
-func ParseSemanticFunctionFromFS(fsys fs.FS, generators map[string]llm.Generator, options ...parseSemanticFunctionFromFSOption) (function *Function, err error) {
-	optionProperties := parseSemanticFunctionFromFSOptionProperties{
-		createSemanticFunction: NewDefaultSemanticFunctionCall,
-	}
-	for _, option := range options {
-		option(&optionProperties)
+// withPartials adds shared partial templates to the semantic function's templates
+func withPartials(partials *template.Template) (option parseSemanticFunctionFromFSOption) {
+	option = func(properties *parseSemanticFunctionFromFSOptionProperties) {
+		properties.partials = partials
 	}
-	// open config file
-	file, err := fsys.Open("config.json")
-	if err != nil {
-		err = fmt.Errorf("opening `config.json` failed: %w", err)
-		return
+	return
+}
+
+// WithCustomCallContext allows to create a custom context aware semantic function call while parsing a semantic function with ParseSemanticFunctionFromFS
+func WithCustomCallContext(createSemanticFunctionCall CreateSemanticFunctionCallContext) (option parseSemanticFunctionFromFSOption) {
+	option = func(properties *parseSemanticFunctionFromFSOptionProperties) {
+		properties.createSemanticFunction = createSemanticFunctionCall
 	}
-	defer file.Close()
+	return
+}
 
+// ParseFunctionFromFS finds "config.json" (or "config.yaml") with function comfiguration.
+// Prompt templates will be created from "*.tmpl" files with at least "skprompt.tmpl" is needed.
+// An optional "system.tmpl" and few-shot examples in "examples.jsonl" precede the prompt as system, user and assistant messages.
+func ParseSemanticFunctionFromFS(fsys fs.FS, generators map[string]llm.Generator, options ...parseSemanticFunctionFromFSOption) (function *Function, err error) {
 	// read config file
-	data, err := io.ReadAll(file)
+	data, configFile, err := readConfig(fsys, ".", "config")
 	if err != nil {
-		err = fmt.Errorf("reading `config.json` failed: %w", err)
 		return
 	}
+	promptTemplate := func() (*template.Template, error) {
+		return llm.TemplateFromFS(fsys, "*.tmpl")
+	}
+	return parseSemanticFunction(fsys, data, configFile, promptTemplate, ExamplesFileName, generators, options...)
+}
+
+// parseSemanticFunction parses a semantic function from its JSON config and its prompt template. The function's examples
+// are read from fsys, from defaultExamplesFile if the config doesn't reference examples.
+func parseSemanticFunction(fsys fs.FS, data []byte, configFile string, promptTemplate func() (*template.Template, error), defaultExamplesFile string, generators map[string]llm.Generator, options ...parseSemanticFunctionFromFSOption) (function *Function, err error) {
+	optionProperties := parseSemanticFunctionFromFSOptionProperties{
+		createSemanticFunction: NewDefaultSemanticFunctionCallContext,
+	}
+	for _, option := range options {
+		option(&optionProperties)
+	}
 
 	// unmarshal config file
 	var functionConfig functionConfig
 	err = json.Unmarshal(data, &functionConfig)
 	function = functionConfig.Function
 	if err != nil {
-		err = fmt.Errorf("unmarshalling `config.json` failed: %w", err)
+		err = fmt.Errorf("unmarshalling `%s` failed: %w", configFile, err)
+		return
+	}
+	if function == nil {
+		err = fmt.Errorf("invalid function `%s`", configFile)
 		return
 	}
 
@@ -176,25 +333,61 @@ func ParseSemanticFunctionFromFS(fsys fs.FS, generators map[string]llm.Generator
 	}
 
 	// get template
-	template, err := llm.TemplateFromFS(fsys, "*.tmpl")
+	template, err := promptTemplate()
+	if err != nil {
+		return
+	}
+	// function's own templates take precedence over shared partials
+	if err = llm.AddTemplates(template, optionProperties.partials); err != nil {
+		return
+	}
 
 	// create function call
-	function.Call = optionProperties.createSemanticFunction(template, generator)
+	callContext := optionProperties.createSemanticFunction(template, generator)
+	if callContext != nil {
+		// precede the prompt with the function's system message and examples
+		var messages *promptMessages
+		if messages, err = parsePromptMessages(fsys, template, functionConfig.Examples, defaultExamplesFile, generators, functionConfig.Generator); err != nil {
+			return
+		}
+		if messages != nil {
+			callContext = messages.withMessages(callContext)
+		}
+		if len(function.OutputProperties) > 0 {
+			callContext = function.withOutputParsing(callContext, generator)
+		}
+		function.CallContext = callContext
+		function.Call = func(input llm.Content) (output llm.Content, err error) {
+			return callContext(context.Background(), input)
+		}
+	}
 	return
 }
 
 // NewDefaultSemanticFunctionCall creates a new semantic skill function with a prompt template and a generator
 func NewDefaultSemanticFunctionCall(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(input llm.Content) (response llm.Content, err error)) {
-	if promptTemplate == nil {
+	callContext := NewDefaultSemanticFunctionCallContext(promptTemplate, generator)
+	if callContext == nil {
 		return
 	}
 	skillFunc = func(input llm.Content) (output llm.Content, err error) {
-		var promptBuffer bytes.Buffer
-		if err = promptTemplate.Execute(&promptBuffer, input); err != nil {
+		return callContext(context.Background(), input)
+	}
+	return
+}
+
+// NewDefaultSemanticFunctionCallContext creates a new context aware semantic skill function with a prompt template and a generator
+func NewDefaultSemanticFunctionCallContext(promptTemplate *template.Template, generator llm.Generator) (skillFunc func(ctx context.Context, input llm.Content) (response llm.Content, err error)) {
+	if promptTemplate == nil {
+		return
+	}
+	skillFunc = func(ctx context.Context, input llm.Content) (output llm.Content, err error) {
+		prompt, err := ExecuteTemplate(ctx, promptTemplate, input)
+		if err != nil {
 			return
 		}
-		input.Set(promptBuffer.String())
-		return generator.Generate(input)
+		input.Set(prompt)
+		return llm.GenerateContext(ctx, generator, input)
 	}
 	return
 }
@@ -0,0 +1,179 @@
+package gosk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+var (
+	ErrNoKernel      = errors.New("function isn't called by a semantic kernel")
+	ErrRecursiveCall = errors.New("recursive function call")
+)
+
+func init() {
+	// register kernel dependent template functions so that templates using them can be parsed,
+	// they are bound to the calling kernel when the template is executed with ExecuteTemplate
+	llm.RegisterTemplateFuncs(template.FuncMap{
+		"skills": func() ([]*Skill, error) {
+			return nil, ErrNoKernel
+		},
+		"functions": func(skillNames ...string) (map[string]*Function, error) {
+			return nil, ErrNoKernel
+		},
+		"call": func(functionPath string, input ...interface{}) (llm.Content, error) {
+			return nil, ErrNoKernel
+		},
+	})
+}
+
+type kernelContextKey struct{}
+
+// ContextWithKernel returns a context carrying the semantic kernel that calls functions
+func ContextWithKernel(ctx context.Context, sk *SemanticKernel) context.Context {
+	return context.WithValue(ctx, kernelContextKey{}, sk)
+}
+
+// KernelFromContext returns the semantic kernel set with ContextWithKernel, nil if not available
+func KernelFromContext(ctx context.Context) *SemanticKernel {
+	sk, _ := ctx.Value(kernelContextKey{}).(*SemanticKernel)
+	return sk
+}
+
+// ExecuteTemplate executes a prompt template with the partials added to the kernel from context (see ContextWithKernel)
+// and the kernel dependent template functions bound to it:
+//   - skills returns the kernel's skills sorted by name, e.g. {{range skills}}{{.Name}}: {{.Description}}{{end}}
+//   - functions returns the functions of all or given skills by path (`skillName.functionName`),
+//     e.g. {{range $path, $function := functions "fun"}}{{$path}}: {{$function.Description}}{{end}}
+//   - call calls a function by path with the template's content or given input and the content's properties,
+//     e.g. {{call "time.today"}} or {{call "writer.translate" (.Property "text")}}
+func ExecuteTemplate(ctx context.Context, promptTemplate *template.Template, data interface{}) (string, error) {
+	sk := KernelFromContext(ctx)
+	if sk == nil {
+		return llm.ExecuteTemplate(promptTemplate, data)
+	}
+	boundTemplate, err := promptTemplate.Clone()
+	if err != nil {
+		return "", err
+	}
+	if err = sk.addPartials(boundTemplate); err != nil {
+		return "", err
+	}
+	return llm.ExecuteTemplate(boundTemplate.Funcs(sk.templateFuncs(ctx, data)), data)
+}
+
+// AddTemplatePartials parses the templates matching the patterns as partials that are available in the prompt templates
+// of all functions called by the kernel, e.g. with {{template "persona" .}}. Templates defined by a function or its skill
+// take precedence, partials added later take precedence over partials added before.
+func (sk *SemanticKernel) AddTemplatePartials(fsys fs.FS, patterns ...string) error {
+	partials, err := llm.TemplatesFromFS(fsys, patterns...)
+	if err != nil {
+		return err
+	}
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.partials = append(sk.partials, partials)
+	return nil
+}
+
+// addPartials adds the kernel's partials to the template's set
+func (sk *SemanticKernel) addPartials(promptTemplate *template.Template) error {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	for i := len(sk.partials) - 1; i >= 0; i-- {
+		if err := llm.AddTemplates(promptTemplate, sk.partials[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFuncs returns the template functions bound to the kernel and the template's data
+func (sk *SemanticKernel) templateFuncs(ctx context.Context, data interface{}) template.FuncMap {
+	return template.FuncMap{
+		"skills": func() ([]*Skill, error) {
+			return sk.Skills(), nil
+		},
+		"functions": func(skillNames ...string) (map[string]*Function, error) {
+			skills := sk.Skills()
+			if len(skillNames) > 0 {
+				skills = make([]*Skill, 0, len(skillNames))
+				for _, skillName := range skillNames {
+					skill, err := sk.FindSkill(skillName)
+					if err != nil {
+						return nil, err
+					}
+					skills = append(skills, skill)
+				}
+			}
+			functions := map[string]*Function{}
+			for _, skill := range skills {
+				for functionName, function := range skill.Functions {
+					functions[skill.Name+"."+functionName] = function
+				}
+			}
+			return functions, nil
+		},
+		"call": func(functionPath string, input ...interface{}) (llm.Content, error) {
+			functions, err := sk.FindFunctions(functionPath)
+			if err != nil {
+				return nil, err
+			}
+			content, _ := data.(llm.Content)
+			// called functions don't stream into the calling function's stream
+			return sk.call(llm.ContextWithStream(ctx, nil), templateCallInput(content, input...), functions[0])
+		},
+	}
+}
+
+// templateCallInput creates the input of a function called from a template with the template's content properties
+// and either the given input or the content's value as value
+func templateCallInput(content llm.Content, input ...interface{}) llm.Content {
+	callInput := llm.NewContent()
+	if content != nil {
+		for name, property := range content.Properties() {
+			switch name {
+			case "role", "name", "predecessor":
+				continue
+			}
+			callInput.With(name, property.Value())
+		}
+		callInput.Set(content.Value())
+	}
+	switch {
+	case len(input) == 1:
+		value := input[0]
+		if property, ok := value.(llm.ContentProperty); ok {
+			value = property.Value()
+		}
+		callInput.Set(value)
+	case len(input) > 1:
+		callInput.Set(input)
+	}
+	return callInput
+}
+
+type callStackContextKey struct{}
+
+// contextWithCall returns a context with the function pushed onto the call stack or an error if the function
+// is already on the call stack
+func contextWithCall(ctx context.Context, function *Function) (context.Context, error) {
+	stack, _ := ctx.Value(callStackContextKey{}).([]*Function)
+	for i, called := range stack {
+		if called != function {
+			continue
+		}
+		names := make([]string, 0, len(stack)-i+1)
+		for _, f := range append(stack[i:], function) {
+			names = append(names, "`"+f.Name+"`")
+		}
+		return ctx, fmt.Errorf("%w: %s", ErrRecursiveCall, strings.Join(names, " -> "))
+	}
+	stack = append(stack[:len(stack):len(stack)], function)
+	return context.WithValue(ctx, callStackContextKey{}, stack), nil
+}
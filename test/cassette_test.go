@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/cassette"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+	"github.com/mfmayer/gosk/pkg/skills/fun"
+	"github.com/mfmayer/gosk/pkg/skills/writer"
+)
+
+func callJokeTranslation(t *testing.T, registration llm.RegistrationFunc, subject string) (llm.Content, error) {
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(registration)
+	if err := kernel.RegisterSkills(fun.Register, writer.Register); err != nil {
+		t.Fatal(err)
+	}
+	functions, err := kernel.FindFunctions("fun.joke", "writer.translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kernel.Call(llm.NewContent(subject).With("language", "german"), functions...)
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "joke.jsonl")
+
+	// record a session with the (live) generator
+	recorder, err := cassette.Open(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := mock.New().Respond("A joke about flowers", "Ein Witz über Blumen")
+	recorded, err := callJokeTranslation(t, recorder.WrapRegistration(generator.RegisterAs("gpt")), "flowers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay the session without generator responses
+	player, err := cassette.Open(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayGenerator := mock.New()
+	replayed, err := callJokeTranslation(t, player.WrapRegistration(replayGenerator.RegisterAs("gpt")), "flowers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.String() != recorded.String() || replayed.Role() != recorded.Role() {
+		t.Fatalf("replayed response %v differs from recorded response %v", replayed, recorded)
+	}
+	if len(replayGenerator.Calls()) != 0 {
+		t.Fatal("generator must not be called while replaying")
+	}
+
+	// different requests are not found
+	_, err = callJokeTranslation(t, player.WrapRegistration(replayGenerator.RegisterAs("gpt")), "dinosaurs")
+	if !errors.Is(err, cassette.ErrInteractionNotFound) {
+		t.Fatalf("expected ErrInteractionNotFound, got %v", err)
+	}
+}
+
+func TestCassetteEmbeddings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.jsonl")
+	texts := []string{"tulips in the garden", "rain clouds"}
+	recorder, err := cassette.Open(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := recorder.Wrap(mock.New()).Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// embeddings are replayed without generator
+	player, err := cassette.Open(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := player.Wrap(nil).Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Fatalf("replayed embeddings %v differ from recorded embeddings %v", replayed, recorded)
+	}
+	if _, err = player.Wrap(nil).Embed(context.Background(), texts[:1]); !errors.Is(err, cassette.ErrInteractionNotFound) {
+		t.Fatalf("expected ErrInteractionNotFound, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mfmayer/gosk/pkg/llm"
@@ -31,3 +33,151 @@ func TestPredecessor(t *testing.T) {
 	bar := c2.Property("foo")
 	t.Log(bar.Value())
 }
+
+func TestContentJSONRoundTrip(t *testing.T) {
+	system := llm.NewContent("You are a helpful bot.").SetRole(llm.RoleSystem).With("user.name", "John")
+	user := llm.NewContent("Hello!").SetRole(llm.RoleUser).SetName("john").WithPredecessor(system)
+	assistant := llm.NewContent(map[string]interface{}{"answer": "Hi John!"}).SetRole(llm.RoleAssistant).WithPredecessor(user)
+
+	restored, err := llm.UnmarshalContent(assistant.JSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored.JSON()) != string(assistant.JSON()) {
+		t.Errorf("expected %s, got %s", assistant.JSON(), restored.JSON())
+	}
+	if restored.Role() != llm.RoleAssistant || !strings.Contains(restored.String(), "Hi John!") {
+		t.Errorf("unexpected assistant content: %s", restored.JSON())
+	}
+	restoredUser := restored.Predecessor()
+	if restoredUser == nil || restoredUser.Role() != llm.RoleUser || restoredUser.Name() != "john" || restoredUser.String() != "Hello!" {
+		t.Fatalf("unexpected user content: %v", restoredUser)
+	}
+	restoredSystem := restoredUser.Predecessor()
+	if restoredSystem == nil || restoredSystem.Role() != llm.RoleSystem || restoredSystem.Predecessor() != nil {
+		t.Fatalf("unexpected system content: %v", restoredSystem)
+	}
+	// properties are found along the restored chain
+	if name := restored.Property("user.name").String(); name != "John" {
+		t.Errorf("expected property `user.name` to be `John`, got `%s`", name)
+	}
+
+	for _, data := range []string{`null`, `[]`, `{"role": 1}`, `{"predecessor": "foo"}`} {
+		if _, err := llm.UnmarshalContent([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestContentStringRole(t *testing.T) {
+	c := llm.NewContent("hi").With("role", "assistant")
+	if c.Role() != llm.RoleAssistant {
+		t.Errorf("expected role `assistant`, got `%s`", c.Role())
+	}
+}
+
+func TestContentPaths(t *testing.T) {
+	c := llm.NewContent(`{"items": [{"name": "a"}, {"name": "b"}]}`).
+		With("results", []map[string]interface{}{{"title": "first"}, {"title": "second"}}).
+		With("tags", []string{"x", "y"})
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{"results.0.title", "first"},
+		{"results[1].title", "second"},
+		{"results[-1].title", "second"},
+		{"tags.1", "y"},
+		{"results.*.title", []interface{}{"first", "second"}},
+		{".items.*.name", []interface{}{"a", "b"}},
+		{"results.2.title", nil},
+		{"tags.z", nil},
+	}
+	for _, test := range tests {
+		if value := c.Property(test.path).Value(); !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.path, test.expected, value)
+		}
+	}
+
+	// append, set by index and for all elements, delete
+	c.With("tags[]", "z").
+		With("results[0].title", "changed").
+		With("results.*.seen", true).
+		With("list[]", "new").
+		Delete("results.1").
+		Delete("foo")
+	if tags := c.Property("tags").Value(); !reflect.DeepEqual(tags, []interface{}{"x", "y", "z"}) {
+		t.Errorf("expected appended tag, got %#v", tags)
+	}
+	if results := c.Property("results").Value(); !reflect.DeepEqual(results, []interface{}{map[string]interface{}{"title": "changed", "seen": true}}) {
+		t.Errorf("unexpected results %#v", results)
+	}
+	if list := c.Property("list").Value(); !reflect.DeepEqual(list, []interface{}{"new"}) {
+		t.Errorf("expected created list, got %#v", list)
+	}
+	c.Delete("tags")
+	if c.Property("tags").Value() != nil {
+		t.Error("expected deleted property")
+	}
+
+	// properties are still found in predecessors
+	next := llm.NewContent("next").WithPredecessor(c)
+	if title := next.Property("results.0.title").String(); title != "changed" {
+		t.Errorf("expected title of predecessor, got `%s`", title)
+	}
+}
+
+func TestContentPathsInvalid(t *testing.T) {
+	// invalid indices leave lists unchanged, indices of missing properties create lists
+	c := llm.NewContent("value").
+		With("items", []interface{}{"a", "b", "c"}).
+		With("items[-10]", "x").
+		With("items.4", "x").
+		With("items.1000000", "x").
+		With("items.name", "x").
+		With("list.1", "y").
+		With("list.0", "z")
+	if items := c.Property("items").Value(); !reflect.DeepEqual(items, []interface{}{"a", "b", "c"}) {
+		t.Errorf("expected unchanged list, got %#v", items)
+	}
+	if list := c.Property("list").Value(); !reflect.DeepEqual(list, []interface{}{"z"}) {
+		t.Errorf("expected created list, got %#v", list)
+	}
+
+	// lists of the caller and their elements aren't modified
+	orig := []interface{}{"a", "b", "c"}
+	c.With("orig", orig).Delete("orig.0").With("orig[1]", "x").With("orig.*", "y").Delete("orig.*")
+	if !reflect.DeepEqual(orig, []interface{}{"a", "b", "c"}) {
+		t.Errorf("expected caller's list to be unchanged, got %#v", orig)
+	}
+	results := []map[string]interface{}{{"title": "first"}, {"title": "second"}}
+	c.With("results", results).
+		With("results[0].title", "changed").
+		With("results.*.seen", true).
+		Delete("results.1.title")
+	if !reflect.DeepEqual(results, []map[string]interface{}{{"title": "first"}, {"title": "second"}}) {
+		t.Errorf("expected caller's maps to be unchanged, got %#v", results)
+	}
+	if title := c.Property("results.0.title").String(); title != "changed" || c.Property("results.1.title").Value() != nil {
+		t.Errorf("unexpected results %#v", c.Property("results").Value())
+	}
+	c.Delete("results.0.title")
+	if results[0]["title"] != "first" || c.Property("results.0.title").Value() != nil {
+		t.Errorf("expected title to be deleted from the copy only, got %#v", results)
+	}
+
+	// root wildcards and appends don't touch the content's value, role, name and predecessor
+	system := llm.NewContent("system").SetRole(llm.RoleSystem)
+	w := llm.NewContent("question").SetRole(llm.RoleUser).SetName("jane").With("topic", "pirates").WithPredecessor(system)
+	w.With("*", "q").With("[]", "r")
+	if w.Predecessor() == nil || w.Role() != llm.RoleUser || w.Name() != "jane" || w.String() != "question" {
+		t.Fatalf("expected reserved keys to be unchanged: %s", w.JSON())
+	}
+	if topic := w.Property("topic").String(); topic != "q" {
+		t.Errorf("expected wildcard to set properties, got `%s`", topic)
+	}
+	w.Delete("*")
+	if w.Predecessor() == nil || w.Role() != llm.RoleUser || w.Property("topic").Value() != nil {
+		t.Errorf("expected wildcard to delete properties only: %s", w.JSON())
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+const jokeExamples = `{"input": "tulips in the garden", "output": "Why did the tulip blush?"}
+{"input": "cats chasing mice", "output": "Why did the cat sit on the computer?"}
+{"input": "rain clouds and weather", "output": "What did one cloud say to the other?"}
+{"input": "dogs and their bones", "output": "Why did the dog bury its bone?"}
+`
+
+// exampleInputs calls the function and returns the inputs of the examples that preceded the prompt
+func exampleInputs(t *testing.T, generator *mock.Generator, function *gosk.Function, input string) (inputs []string) {
+	t.Helper()
+	generator.Reset()
+	generator.Default("A joke")
+	if _, err := function.Call(llm.NewContent(input)); err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range chain(generator.Calls()[0].Input.Predecessor()) {
+		if message.Role() == llm.RoleUser {
+			inputs = append(inputs, message.String())
+		}
+	}
+	return
+}
+
+func TestExampleSelection(t *testing.T) {
+	generator := mock.New()
+	generators := map[string]llm.Generator{"default": generator}
+	parse := func(examplesConfig string) (*gosk.Function, error) {
+		fsys := fstest.MapFS{
+			"config.json":   {Data: []byte(`{"name": "joke", "description": "Tell a joke", "generator": "default", "examples": ` + examplesConfig + `}`)},
+			"skprompt.tmpl": {Data: []byte(`{{.}}`)},
+			"jokes.jsonl":   {Data: []byte(jokeExamples)},
+		}
+		return gosk.ParseSemanticFunctionFromFS(fsys, generators)
+	}
+
+	function, err := parse(`{"file": "jokes.jsonl", "strategy": "first", "count": 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inputs := exampleInputs(t, generator, function, "birds"); len(inputs) != 2 || inputs[0] != "tulips in the garden" || inputs[1] != "cats chasing mice" {
+		t.Errorf("unexpected first examples: %v", inputs)
+	}
+
+	function, err = parse(`{"file": "jokes.jsonl", "strategy": "random", "count": 2, "seed": 7}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := exampleInputs(t, generator, function, "birds")
+	second := exampleInputs(t, generator, function, "birds")
+	if len(first) != 2 || first[0] != second[0] || first[1] != second[1] {
+		t.Errorf("seeded random examples differ: %v, %v", first, second)
+	}
+
+	// seed 0 selects the same examples, too
+	function, err = parse(`{"file": "jokes.jsonl", "strategy": "random", "count": 2, "seed": 0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first = exampleInputs(t, generator, function, "birds")
+	for i := 0; i < 5; i++ {
+		if inputs := exampleInputs(t, generator, function, "birds"); inputs[0] != first[0] || inputs[1] != first[1] {
+			t.Fatalf("random examples with seed 0 differ: %v, %v", first, inputs)
+		}
+	}
+
+	function, err = parse(`{"file": "jokes.jsonl", "strategy": "similar", "count": 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inputs := exampleInputs(t, generator, function, "dogs and bones"); len(inputs) != 2 || inputs[1] != "dogs and their bones" {
+		t.Errorf("unexpected similar examples: %v", inputs)
+	}
+	if inputs := exampleInputs(t, generator, function, "weather with rain"); inputs[1] != "rain clouds and weather" {
+		t.Errorf("most similar example isn't last: %v", inputs)
+	}
+
+	if _, err = parse(`{"file": "jokes.jsonl", "strategy": "best"}`); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+
+	// the function's generator is named if it can't create embeddings
+	generators["default"] = wordStreamer{}
+	if _, err = parse(`{"file": "jokes.jsonl", "strategy": "similar"}`); err == nil || !strings.Contains(err.Error(), "generator `default`") {
+		t.Errorf("expected error naming the generator, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestFunctionCalling(t *testing.T) {
+	generator := mock.New().DefaultFunc(func(input llm.Content) (llm.Content, error) {
+		if input.Role() == llm.RoleFunctionResponse {
+			return llm.NewContent("The weather in Stuttgart is " + input.String()), nil
+		}
+		return llm.NewContent(`{"input":"Stuttgart","unit":"celsius"}`).
+			SetRole(llm.RoleFunctionCall).
+			SetName("weather-get"), nil
+	})
+
+	kernel := gosk.NewKernel()
+	err := kernel.AddSkills(&gosk.Skill{
+		Name: "weather",
+		Functions: map[string]*gosk.Function{
+			"get": {
+				Description: "Get the current weather",
+				InputProperties: map[string]*gosk.Parameter{
+					"":     {Description: "Location", Type: gosk.TypeString, Required: true},
+					"unit": {Description: "Temperature unit", Type: gosk.TypeString, Enum: []string{"celsius", "fahrenheit"}},
+				},
+				CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+					return llm.NewContent("sunny at 20 degrees " + input.Property("unit").String() + " in " + input.String()), nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assistant := &gosk.Function{
+		Name: "assistant",
+		CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+			return llm.GenerateContext(ctx, generator, input)
+		},
+	}
+
+	ctx, err := kernel.ContextWithFunctions(context.Background(), true, "weather.get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := kernel.CallContext(ctx, llm.NewContent("How is the weather?").SetRole(llm.RoleUser), assistant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "The weather in Stuttgart is sunny at 20 degrees celsius in Stuttgart" {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	// response follows function response and call
+	if response.Predecessor().Role() != llm.RoleFunctionResponse || response.Predecessor().Predecessor().Role() != llm.RoleFunctionCall {
+		t.Fatal("response doesn't follow function response and call")
+	}
+
+	calls := generator.Calls()
+	if len(calls) != 2 || len(calls[0].Functions) != 1 {
+		t.Fatalf("expected 2 calls with advertised function, got %+v", calls)
+	}
+	definition := calls[0].Functions[0]
+	required, _ := definition.Parameters["required"].([]string)
+	if definition.Name != "weather-get" || len(required) != 1 || required[0] != "input" {
+		t.Fatalf("unexpected function definition: %+v", definition)
+	}
+
+	// without execution the function call is returned
+	ctx, _ = kernel.ContextWithFunctions(context.Background(), false, "weather.get")
+	response, err = kernel.CallContext(ctx, llm.NewContent("How is the weather?").SetRole(llm.RoleUser), assistant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arguments, _ := response.Value().(map[string]interface{})
+	if response.Role() != llm.RoleFunctionCall || arguments["unit"] != "celsius" {
+		t.Fatalf("expected function call, got %v", response)
+	}
+}
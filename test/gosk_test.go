@@ -1,11 +1,16 @@
 package test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/mfmayer/gosk"
 	"github.com/mfmayer/gosk/pkg/gpt"
 	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
 	"github.com/mfmayer/gosk/pkg/skills/fun"
 	"github.com/mfmayer/gosk/pkg/skills/writer"
 )
@@ -48,3 +53,78 @@ func TestFunSkill(t *testing.T) {
 	}
 	t.Log(result.String())
 }
+
+func TestCallContextCancelled(t *testing.T) {
+	kernel := gosk.NewKernel()
+	calls := 0
+	function := &gosk.Function{
+		Name: "count",
+		CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+			calls++
+			return llm.NewContent(calls), nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := kernel.CallContext(ctx, llm.NewContent("start"), function, function)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || response.Value() != 2 {
+		t.Fatalf("expected 2 calls, got %d (response: %v)", calls, response)
+	}
+	cancel()
+	_, err = kernel.CallContext(ctx, llm.NewContent("start"), function)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("function must not be called with cancelled context")
+	}
+}
+
+func TestKernelConcurrentRegistry(t *testing.T) {
+	kernel := gosk.NewKernel()
+	newSkill := func(answer string) *gosk.Skill {
+		return &gosk.Skill{Name: "answer", Functions: map[string]*gosk.Function{
+			"get": {CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+				return llm.NewContent(answer), nil
+			}},
+		}}
+	}
+	if err := kernel.AddSkills(newSkill("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kernel.AddSkills(newSkill("b")); err == nil {
+		t.Fatal("adding an existing skill must fail")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			kernel.ReplaceSkill(newSkill(fmt.Sprint(i)))
+			typeID := fmt.Sprintf("generator%d", i)
+			kernel.RegisterGenerators(mock.New().RegisterAs(typeID))
+			kernel.UnregisterGenerator(typeID)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := kernel.CallWithName(llm.NewContent(), "answer", "get"); err != nil {
+				t.Error(err)
+			}
+			kernel.Skills()
+		}()
+	}
+	wg.Wait()
+
+	if err := kernel.RemoveSkill("answer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kernel.FindSkill("answer"); !errors.Is(err, gosk.ErrSkillNotFound) {
+		t.Fatalf("expected ErrSkillNotFound, got %v", err)
+	}
+	if err := kernel.UnregisterGenerator("generator0"); !errors.Is(err, gosk.ErrGeneratorNotFound) {
+		t.Fatalf("expected ErrGeneratorNotFound, got %v", err)
+	}
+}
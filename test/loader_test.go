@@ -0,0 +1,45 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestLoadSkillsFromFS(t *testing.T) {
+	generator := mock.New().Echo()
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+
+	err := kernel.LoadSkillsFromFS(os.DirFS("testdata/skills"))
+	var skillErr *gosk.SkillError
+	if !errors.As(err, &skillErr) || skillErr.Path != "broken" {
+		t.Fatalf("expected error for broken skill, got %v", err)
+	}
+
+	response, err := kernel.CallWithName(llm.NewContent("Ida"), "greeting", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "Say hello to Ida." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	response, err = kernel.CallWithName(llm.NewContent("flowers"), "poems", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "Write a haiku about flowers." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	response, err = kernel.CallWithName(llm.NewContent("time"), "riddles", "riddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "Ask a riddle about time." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+	"github.com/mfmayer/gosk/pkg/skills/chat"
+)
+
+// chain returns the content's predecessor chain in chronological order
+func chain(content llm.Content) (contents []llm.Content) {
+	for ; content != nil; content = content.Predecessor() {
+		contents = append([]llm.Content{content}, contents...)
+	}
+	return
+}
+
+func TestMultiMessageTemplates(t *testing.T) {
+	generator := mock.New().Default("Why did the rose blush?")
+	fsys := fstest.MapFS{
+		"config.json":    {Data: []byte(`{"name": "joke", "description": "Tell a joke", "generator": "default"}`)},
+		"system.tmpl":    {Data: []byte(`You tell jokes in {{.Property "style" | default "a friendly"}} style.`)},
+		"examples.jsonl": {Data: []byte("{\"input\": \"Joke about: tulips\", \"output\": \"Why did the tulip blush?\"}\n\n{\"input\": \"Joke about: cacti\", \"output\": \"Why was the cactus late?\"}\n")},
+		"skprompt.tmpl":  {Data: []byte(`Joke about: {{.}}`)},
+	}
+	function, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = function.Call(llm.NewContent("roses").With("style", "a dry")); err != nil {
+		t.Fatal(err)
+	}
+	messages := chain(generator.Calls()[0].Input)
+	expected := []struct {
+		role llm.ContentRole
+		text string
+	}{
+		{llm.RoleSystem, "You tell jokes in a dry style."},
+		{llm.RoleUser, "Joke about: tulips"},
+		{llm.RoleAssistant, "Why did the tulip blush?"},
+		{llm.RoleUser, "Joke about: cacti"},
+		{llm.RoleAssistant, "Why was the cactus late?"},
+		{llm.RoleUser, "Joke about: roses"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(messages))
+	}
+	for i, message := range messages {
+		if message.Role() != expected[i].role || message.String() != expected[i].text {
+			t.Errorf("message %d: expected %s `%s`, got %s `%s`", i, expected[i].role, expected[i].text, message.Role(), message)
+		}
+	}
+}
+
+func TestChatSystemMessage(t *testing.T) {
+	generator := mock.New().Respond("Ahoy!", "Arr!")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(chat.Register); err != nil {
+		t.Fatal(err)
+	}
+	response, err := kernel.CallWithName(llm.NewContent("Hello").With("attitude", "a pirate"), "chat", "chatgpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = kernel.CallWithName(llm.NewContent("Who are you?").SetRole(llm.RoleUser).WithPredecessor(response), "chat", "chatgpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := chain(response)
+	if len(messages) != 5 || messages[0].Role() != llm.RoleSystem || messages[4].String() != "Arr!" {
+		t.Fatalf("unexpected conversation: %v", messages)
+	}
+}
+
+func TestSystemMessagesInCallChain(t *testing.T) {
+	generator := mock.New().Echo()
+	newFunction := func(name string, system string) *gosk.Function {
+		fsys := fstest.MapFS{
+			"config.json":   {Data: []byte(`{"name": "` + name + `", "description": "Function ` + name + `", "generator": "default"}`)},
+			"system.tmpl":   {Data: []byte(system)},
+			"skprompt.tmpl": {Data: []byte(`{{.}}`)},
+		}
+		function, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": generator})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return function
+	}
+	input := llm.NewContent("hello")
+	if _, err := gosk.NewKernel().Call(input, newFunction("a", "SYSTEM A"), newFunction("b", "SYSTEM B")); err != nil {
+		t.Fatal(err)
+	}
+	// each function precedes its input with its own system message
+	calls := generator.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	for i, system := range []string{"SYSTEM A", "SYSTEM B"} {
+		if messages := chain(calls[i].Input); len(messages) != 2 || messages[0].String() != system {
+			t.Errorf("call %d: expected system message `%s`, got %v", i, system, messages)
+		}
+	}
+	if input.Predecessor() != nil || input.Role() != llm.RoleEmpty {
+		t.Errorf("expected caller's input to be unchanged: %s", input.JSON())
+	}
+}
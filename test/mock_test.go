@@ -0,0 +1,55 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+	"github.com/mfmayer/gosk/pkg/skills/fun"
+	"github.com/mfmayer/gosk/pkg/skills/writer"
+)
+
+func TestKernelWithMock(t *testing.T) {
+	generator := mock.New().
+		When("(?i)^translate", "Ein Witz über Blumen").
+		Respond("A joke about flowers")
+	kernel := gosk.NewKernel()
+	// redirect skill configs with `"typeID": "gpt"` to the mock generator
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	err := kernel.RegisterSkills(fun.Register, writer.Register)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	functions, err := kernel.FindFunctions("fun.joke", "writer.translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := kernel.Call(llm.NewContent("flowers").With("language", "german"), functions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "Ein Witz über Blumen" {
+		t.Fatalf("unexpected result: %s", result)
+	}
+
+	calls := generator.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if !strings.Contains(calls[0].Prompt, "Topic: flowers") || calls[0].Config["model"] != "gpt-3.5-turbo" {
+		t.Fatalf("unexpected first call: %+v", calls[0])
+	}
+	if !strings.Contains(calls[1].Prompt, "german") || !strings.Contains(calls[1].Prompt, "A joke about flowers") {
+		t.Fatalf("unexpected second call: %+v", calls[1])
+	}
+
+	// no responses left
+	_, err = kernel.Call(llm.NewContent("dinosaurs"), functions[0])
+	if !errors.Is(err, mock.ErrNoResponse) {
+		t.Fatalf("expected ErrNoResponse, got %v", err)
+	}
+}
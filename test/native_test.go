@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+type weatherInput struct {
+	Location string `json:"input" description:"The location" required:"true"`
+	Unit     string `json:"unit" description:"Temperature unit" enum:"celsius,fahrenheit" default:"celsius"`
+	Days     int    `json:"days" description:"Number of forecast days" default:"1"`
+}
+
+type weatherOutput struct {
+	Summary     string  `json:"summary"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestNativeFunction(t *testing.T) {
+	function, err := gosk.NewNativeFunction("forecast", "Get the weather forecast",
+		func(ctx context.Context, input weatherInput) (weatherOutput, error) {
+			return weatherOutput{
+				Summary:     fmt.Sprintf("%d days of sun in %s", input.Days, input.Location),
+				Temperature: 21.5,
+			}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := function.InputProperties[""]
+	unit := function.InputProperties["unit"]
+	days := function.InputProperties["days"]
+	if location == nil || !location.Required || location.Type != gosk.TypeString {
+		t.Fatalf("unexpected location parameter: %+v", location)
+	}
+	if unit == nil || len(unit.Enum) != 2 || unit.Default != "celsius" {
+		t.Fatalf("unexpected unit parameter: %+v", unit)
+	}
+	if days == nil || days.Type != gosk.TypeInteger || days.Default != int64(1) {
+		t.Fatalf("unexpected days parameter: %+v", days)
+	}
+
+	kernel := gosk.NewKernel()
+	kernel.AddSkills(&gosk.Skill{Name: "weather", Functions: map[string]*gosk.Function{"forecast": function}})
+	response, err := kernel.CallWithName(llm.NewContent("Stuttgart").With("days", 3), "weather", "forecast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Property("summary").String() != "3 days of sun in Stuttgart" || response.Property("temperature").Value() != 21.5 {
+		t.Fatalf("unexpected response: %s", response.JSON())
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+const ratedJokeConfig = `{
+  "name": "ratedJoke",
+  "description": "Tell a joke and rate it",
+  "inputProperties": {
+    "": {"description": "Topic", "required": true}
+  },
+  "outputProperties": {
+    "joke": {"description": "The joke", "type": "string", "required": true},
+    "rating": {"description": "Rating from 1 to 10", "type": "integer", "minimum": 1, "maximum": 10, "required": true}
+  },
+  "generator": "default"
+}`
+
+func TestOutputProperties(t *testing.T) {
+	generator := mock.New().
+		Respond("Sure! Here it is:\n```json\n{\"joke\": \"Why did the tulip blush?\", \"rating\": \"7\"}\n```").
+		Respond("Why did the rose blush? I don't know.")
+	fsys := fstest.MapFS{
+		"config.json":   {Data: []byte(ratedJokeConfig)},
+		"skprompt.tmpl": {Data: []byte("Tell a joke about {{.}} as JSON.")},
+	}
+	function, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if function.OutputProperties["rating"].Type != gosk.TypeInteger {
+		t.Fatalf("unexpected output properties: %+v", function.OutputProperties)
+	}
+
+	response, err := function.Call(llm.NewContent("flowers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Property("joke").String() != "Why did the tulip blush?" {
+		t.Errorf("unexpected joke: %s", response.Property("joke"))
+	}
+	if rating := response.Property("rating").Value(); rating != int64(7) {
+		t.Errorf("unexpected rating: %#v", rating)
+	}
+
+	_, err = function.Call(llm.NewContent("roses"))
+	var outputErr *gosk.OutputError
+	if !errors.As(err, &outputErr) || !errors.Is(err, gosk.ErrInvalidOutput) {
+		t.Fatalf("expected output error, got: %v", err)
+	}
+	if outputErr.Output != "Why did the rose blush? I don't know." {
+		t.Errorf("unexpected raw output: %s", outputErr.Output)
+	}
+}
+
+func TestOutputRepair(t *testing.T) {
+	generator := mock.New().
+		Respond(`{"joke": "Why did the tulip blush?", "rating": 11}`).
+		Respond(`{"joke": "Why did the tulip blush?", "rating": 8}`).
+		Respond("no joke").
+		Respond("still no joke")
+	config := strings.Replace(ratedJokeConfig, `"generator"`, `"repairAttempts": 1, "generator"`, 1)
+	fsys := fstest.MapFS{
+		"config.json":   {Data: []byte(config)},
+		"skprompt.tmpl": {Data: []byte("Tell a joke about {{.}} as JSON.")},
+	}
+	function, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := function.Call(llm.NewContent("flowers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rating := response.Property("rating").Value(); rating != int64(8) {
+		t.Errorf("unexpected rating: %#v", rating)
+	}
+	calls := generator.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	followUp := calls[1].Input
+	if followUp.Role() != llm.RoleUser || !strings.Contains(followUp.String(), "maximum") {
+		t.Errorf("unexpected follow-up: %s", followUp)
+	}
+	if invalid := followUp.Predecessor(); invalid == nil || invalid.Role() != llm.RoleAssistant || !strings.Contains(invalid.String(), `"rating":11`) {
+		t.Errorf("unexpected invalid output in follow-up chain: %v", invalid)
+	}
+
+	_, err = function.Call(llm.NewContent("roses"))
+	var repairErr *gosk.RepairError
+	if !errors.As(err, &repairErr) || len(repairErr.Attempts) != 2 {
+		t.Fatalf("expected repair error with 2 attempts, got: %v", err)
+	}
+	if repairErr.Attempts[0].Output != "no joke" || repairErr.Attempts[1].Output != "still no joke" {
+		t.Errorf("unexpected attempts: %v", err)
+	}
+	if !errors.Is(err, gosk.ErrInvalidOutput) {
+		t.Errorf("expected invalid output error: %v", err)
+	}
+}
+
+func TestOutputNull(t *testing.T) {
+	function := &gosk.Function{Name: "rate", OutputProperties: map[string]*gosk.Parameter{
+		"rating": {Type: gosk.TypeInteger, Default: 5},
+	}}
+	for _, output := range []string{"null", "```json\nnull\n```"} {
+		var outputErr *gosk.OutputError
+		if err := function.ParseOutput(llm.NewContent(output)); !errors.As(err, &outputErr) {
+			t.Errorf("%q: expected output error, got: %v", output, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestTemplatePartials(t *testing.T) {
+	generator := mock.New().Echo()
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	skillFS := fstest.MapFS{
+		"config.json":            {Data: []byte(`{"name": "assistant", "description": "An assistant", "templates": "templates", "generators": {"default": {"typeID": "gpt"}}}`)},
+		"templates/persona.tmpl": {Data: []byte(`{{define "persona"}}You are {{.Property "botName" | default "Ida"}}.{{end}}`)},
+		"answer/config.json":     {Data: []byte(`{"name": "answer", "description": "Answer a question", "generator": "default"}`)},
+		"answer/skprompt.tmpl":   {Data: []byte(`{{template "persona" .}} {{template "rules" .}} Answer: {{.}}`)},
+		"greet/config.json":      {Data: []byte(`{"name": "greet", "description": "Greet the user", "generator": "default"}`)},
+		"greet/skprompt.tmpl":    {Data: []byte(`{{template "persona" .}} {{template "greeting" .}}`)},
+		"override/config.json":   {Data: []byte(`{"name": "override", "description": "Own persona", "generator": "default"}`)},
+		"override/skprompt.tmpl": {Data: []byte(`{{template "persona" .}}`)},
+		"override/persona.tmpl":  {Data: []byte(`{{define "persona"}}You are Bob.{{end}}`)},
+	}
+	err := kernel.RegisterSkills(func(generatorFactories llm.NewGeneratorFuncMap) (*gosk.Skill, error) {
+		return gosk.ParseSemanticSkillFromFS(skillFS, generatorFactories)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = kernel.AddTemplatePartials(fstest.MapFS{
+		"rules.tmpl": {Data: []byte(`{{define "rules"}}Be brief.{{end}}`)},
+	}, "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := kernel.CallWithName(llm.NewContent("Why?"), "assistant", "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "You are Ida. Be brief. Answer: Why?" {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	response, err = kernel.CallWithName(llm.NewContent(), "assistant", "override")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "You are Bob." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	_, err = kernel.CallWithName(llm.NewContent(), "assistant", "greet")
+	if !errors.Is(err, llm.ErrMissingTemplate) {
+		t.Fatalf("expected missing template error, got: %v", err)
+	}
+	t.Log(err)
+}
+
+func TestInvalidTemplatePartial(t *testing.T) {
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(mock.New().Echo().RegisterAs("gpt"))
+	skillFS := fstest.MapFS{
+		"config.json":          {Data: []byte(`{"name": "assistant", "description": "An assistant", "generators": {"default": {"typeID": "gpt"}}}`)},
+		"answer/config.json":   {Data: []byte(`{"name": "answer", "description": "Answer a question", "generator": "default"}`)},
+		"answer/skprompt.tmpl": {Data: []byte(`{{template "persona" .}} Answer: {{.}}`)},
+		"answer/persona.tmpl":  {Data: []byte(`{{define "persona"}}You are {{.Property "botName"`)},
+	}
+	// errors of the function's partials must not be hidden by its custom function call
+	createAnswer := func(promptTemplate *template.Template, generator llm.Generator) func(ctx context.Context, input llm.Content) (llm.Content, error) {
+		return func(ctx context.Context, input llm.Content) (llm.Content, error) {
+			return llm.GenerateContext(ctx, generator, input)
+		}
+	}
+	err := kernel.RegisterSkills(func(generatorFactories llm.NewGeneratorFuncMap) (*gosk.Skill, error) {
+		return gosk.ParseSemanticSkillFromFS(skillFS, generatorFactories, gosk.WithCustomCallContextForFunc("answer", createAnswer))
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid partial")
+	}
+	t.Log(err)
+}
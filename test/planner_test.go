@@ -1 +1,219 @@
 package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+	"github.com/mfmayer/gosk/pkg/planner"
+	"github.com/mfmayer/gosk/pkg/skills/fun"
+	"github.com/mfmayer/gosk/pkg/skills/writer"
+)
+
+const testPlan = "Here is the plan:\n```json\n" + `{
+  "steps": [
+    {"id": "joke", "function": "fun.joke", "inputs": {"input": {"value": "flowers"}, "style": {"value": "one-liner"}}},
+    {"id": "translation", "function": "writer.translate", "inputs": {"input": {"ref": "joke"}, "language": {"value": "german"}}}
+  ]
+}` + "\n```"
+
+func TestPlanner(t *testing.T) {
+	generator := mock.New().
+		When("^You are a planner", testPlan).
+		When("(?i)^translate", "Ein Witz über Blumen").
+		When("(?i)joke", "A joke about flowers")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(fun.Register, writer.Register); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := planner.New(kernel, generator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := p.CreatePlan(context.Background(), "Tell me a joke about flowers in german")
+	if err != nil {
+		t.Fatal(err)
+	}
+	planningPrompt := generator.Calls()[0].Prompt
+	if !strings.Contains(planningPrompt, `"name": "fun.joke"`) || !strings.Contains(planningPrompt, `"name": "writer.translate"`) {
+		t.Fatalf("plannable functions missing in prompt: %s", planningPrompt)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(plan.Steps))
+	}
+
+	response, err := plan.Execute(context.Background(), kernel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "Ein Witz über Blumen" || plan.Step("joke").Output.String() != "A joke about flowers" {
+		t.Fatalf("unexpected plan results: %v / %v", plan.Step("joke").Output, response)
+	}
+	translationPrompt := generator.Calls()[2].Prompt
+	if !strings.Contains(translationPrompt, "german") || !strings.Contains(translationPrompt, "A joke about flowers") {
+		t.Fatalf("unexpected translation prompt: %s", translationPrompt)
+	}
+
+	// invalid plans are rejected
+	invalid, err := planner.ParsePlan(`{"steps": [{"function": "writer.translate", "inputs": {"input": {"ref": "joke"}}}, {"function": "chat.chatgpt"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = invalid.Validate(kernel); !errors.Is(err, planner.ErrInvalidPlan) {
+		t.Fatalf("expected ErrInvalidPlan, got %v", err)
+	}
+}
+
+func TestPlanResume(t *testing.T) {
+	generator := mock.New().When("^Write exactly one joke", "A joke about flowers")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(fun.Register, writer.Register); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planner.ParsePlan(testPlan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// translation fails since there is no response for it
+	_, err = plan.Execute(context.Background(), kernel)
+	if !errors.Is(err, mock.ErrNoResponse) {
+		t.Fatalf("expected ErrNoResponse, got %v", err)
+	}
+	if plan.Step("joke").Status != planner.StatusSucceeded || plan.Step("translation").Status != planner.StatusFailed {
+		t.Fatalf("unexpected step status: %s, %s", plan.Step("joke").Status, plan.Step("translation").Status)
+	}
+
+	// store plan and let a human edit it
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := &planner.Plan{}
+	if err = json.Unmarshal(data, stored); err != nil {
+		t.Fatal(err)
+	}
+	stored.Step("translation").Inputs["language"].Value = "french"
+
+	// resume from the failed step with the stored joke
+	generator.When("(?i)^translate", "Une blague sur les fleurs")
+	response, err := stored.Execute(context.Background(), kernel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := generator.Calls()
+	if len(calls) != 3 || !strings.Contains(calls[2].Prompt, "french") || !strings.Contains(calls[2].Prompt, "A joke about flowers") {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if response.String() != "Une blague sur les fleurs" || !stored.Done() {
+		t.Fatalf("unexpected response: %v", response)
+	}
+}
+
+type searchResult struct {
+	Title string `json:"title"`
+}
+
+type searchOutput struct {
+	Results []searchResult `json:"results"`
+}
+
+type shoutInput struct {
+	Text string `json:"input" required:"true"`
+}
+
+func TestPlanListBinding(t *testing.T) {
+	search, err := gosk.NewNativeFunction("search", "Search for texts",
+		func(ctx context.Context, input shoutInput) (searchOutput, error) {
+			return searchOutput{Results: []searchResult{{Title: "first " + input.Text}, {Title: "second " + input.Text}}}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shout, err := gosk.NewNativeFunction("shout", "Shout the text",
+		func(ctx context.Context, input shoutInput) (string, error) {
+			return strings.ToUpper(input.Text), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	search.Plannable, shout.Plannable = true, true
+	kernel := gosk.NewKernel()
+	kernel.AddSkills(&gosk.Skill{Name: "text", Plannable: true, Functions: map[string]*gosk.Function{"search": search, "shout": shout}})
+
+	plan, err := planner.ParsePlan(`{"steps": [
+		{"id": "search", "function": "text.search", "inputs": {"input": {"value": "result"}}},
+		{"id": "shout", "function": "text.shout", "inputs": {"input": {"ref": "search", "path": "results[1].title"}}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := plan.Execute(context.Background(), kernel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "SECOND RESULT" {
+		t.Fatalf("unexpected response: %s", response)
+	}
+	if titles := plan.Step("search").Output.Property("results.*.title").Value(); len(titles.([]interface{})) != 2 {
+		t.Fatalf("unexpected titles: %v", titles)
+	}
+}
+
+func TestPlanResumeWithPropertyBinding(t *testing.T) {
+	fetch := &gosk.Function{Name: "fetch", Description: "Fetch a document", Plannable: true,
+		CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+			return llm.NewContent("document").With("title", "first "+input.String()), nil
+		}}
+	failing := true
+	shout, err := gosk.NewNativeFunction("shout", "Shout the text",
+		func(ctx context.Context, input shoutInput) (string, error) {
+			if failing {
+				return "", errors.New("too quiet")
+			}
+			return strings.ToUpper(input.Text), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shout.Plannable = true
+	kernel := gosk.NewKernel()
+	kernel.AddSkills(&gosk.Skill{Name: "text", Plannable: true, Functions: map[string]*gosk.Function{"fetch": fetch, "shout": shout}})
+
+	plan, err := planner.ParsePlan(`{"steps": [
+		{"id": "fetch", "function": "text.fetch", "inputs": {"input": {"value": "result"}}},
+		{"id": "shout", "function": "text.shout", "inputs": {"input": {"ref": "fetch", "path": "title"}}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = plan.Execute(context.Background(), kernel); err == nil {
+		t.Fatal("expected failing step")
+	}
+
+	// the stored output keeps its properties for the bindings of the resumed plan
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := &planner.Plan{}
+	if err = json.Unmarshal(data, stored); err != nil {
+		t.Fatal(err)
+	}
+	failing = false
+	response, err := stored.Execute(context.Background(), kernel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "FIRST RESULT" || stored.Step("fetch").Output.String() != "document" {
+		t.Fatalf("unexpected response %s of plan %s", response, data)
+	}
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+// syncBuffer is a buffer that can be written and read concurrently
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buffer.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buffer.String()
+}
+
+func writeFile(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually calls condition until it returns true or the timeout is reached
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatal("condition not met in time")
+}
+
+// greetingSkillDir returns a temporary directory with a copy of the greeting skill
+func greetingSkillDir(t *testing.T) string {
+	dir := t.TempDir()
+	for _, file := range []string{"config.json", "hello/config.json", "hello/skprompt.tmpl"} {
+		data, err := os.ReadFile(filepath.Join("testdata/skills/greeting", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, "greeting", file), string(data), time.Now().Add(-time.Hour))
+	}
+	return dir
+}
+
+func TestHotReload(t *testing.T) {
+	dir := greetingSkillDir(t)
+
+	logs := &syncBuffer{}
+	kernel := gosk.NewKernel(gosk.WithHotReload(10*time.Millisecond), gosk.WithLogger(log.New(logs, "", 0)))
+	defer kernel.Close()
+	kernel.RegisterGenerators(mock.New().Echo().RegisterAs("gpt"))
+	if err := kernel.LoadSkillsFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	hello := func() string {
+		response, err := kernel.CallWithName(llm.NewContent("Ida"), "greeting", "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.String()
+	}
+	if response := hello(); response != "Say hello to Ida." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+
+	// changed prompt is reloaded
+	writeFile(t, filepath.Join(dir, "greeting/hello/skprompt.tmpl"), "Say goodbye to {{.}}.", time.Now())
+	eventually(t, func() bool { return hello() == "Say goodbye to Ida." })
+
+	// broken config is logged and the working skill is kept
+	writeFile(t, filepath.Join(dir, "greeting/config.json"), "{", time.Now().Add(time.Minute))
+	eventually(t, func() bool { return strings.Contains(logs.String(), "reloading skill `greeting` failed") })
+	if response := hello(); response != "Say goodbye to Ida." {
+		t.Fatalf("unexpected response: %s", response)
+	}
+}
+
+func TestHotReloadRemovedSkill(t *testing.T) {
+	dir := greetingSkillDir(t)
+	logs := &syncBuffer{}
+	kernel := gosk.NewKernel(gosk.WithHotReload(10*time.Millisecond), gosk.WithLogger(log.New(logs, "", 0)))
+	defer kernel.Close()
+	kernel.RegisterGenerators(mock.New().Echo().RegisterAs("gpt"))
+	if err := kernel.LoadSkillsFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := kernel.RemoveSkill("greeting"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes of removed skills aren't reloaded
+	writeFile(t, filepath.Join(dir, "greeting/hello/skprompt.tmpl"), "Say goodbye to {{.}}.", time.Now())
+	eventually(t, func() bool { return strings.Contains(logs.String(), "skill `greeting` has been removed") })
+	if _, err := kernel.FindSkill("greeting"); !errors.Is(err, gosk.ErrSkillNotFound) {
+		t.Fatalf("expected removed skill, got %v", err)
+	}
+}
+
+func TestHotReloadClose(t *testing.T) {
+	kernel := gosk.NewKernel(gosk.WithHotReload(10 * time.Millisecond))
+	kernel.RegisterGenerators(mock.New().Echo().RegisterAs("gpt"))
+	dir := greetingSkillDir(t)
+	if err := kernel.LoadSkillsFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	// closing repeatedly and concurrently doesn't panic
+	var closing sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		closing.Add(1)
+		go func() {
+			defer closing.Done()
+			kernel.Close()
+		}()
+	}
+	closing.Wait()
+	if err := kernel.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// skills loaded after closing aren't watched
+	if err := kernel.RemoveSkill("greeting"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kernel.LoadSkillsFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := kernel.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
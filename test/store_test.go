@@ -0,0 +1,273 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+	"github.com/mfmayer/gosk/pkg/skills/chat"
+	"github.com/mfmayer/gosk/pkg/store"
+)
+
+// conversationTexts returns the roles and texts of the conversation's contents in chronological order
+func conversationTexts(conversation llm.Content) (texts []string) {
+	for _, content := range llm.Chain(conversation) {
+		texts = append(texts, string(content.Role())+": "+content.String())
+	}
+	return
+}
+
+func testConversationStore(t *testing.T, conversations store.ConversationStore) {
+	defer conversations.Close()
+	if _, err := conversations.Load("first"); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	system := llm.NewContent("Be nice.").SetRole(llm.RoleSystem)
+	user := llm.NewContent("Hello").SetRole(llm.RoleUser).SetName("john").With("language", "german").WithPredecessor(system)
+	if err := conversations.Save("first", user); err != nil {
+		t.Fatal(err)
+	}
+	if err := conversations.Append("first", llm.NewContent("Hallo John").SetRole(llm.RoleAssistant).WithPredecessor(user)); err != nil {
+		t.Fatal(err)
+	}
+	conversation, err := conversations.Load("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"system: Be nice.", "user: Hello", "assistant: Hallo John"}
+	if texts := conversationTexts(conversation); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("expected %v, got %v", expected, texts)
+	}
+	if conversation.Property("language").String() != "german" || conversation.Predecessor().Name() != "john" {
+		t.Errorf("properties and names not restored: %s", conversation.JSON())
+	}
+
+	// forked sessions are continued independently
+	if err := conversations.Fork("first", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conversations.Fork("first", "second"); !errors.Is(err, store.ErrSessionExists) {
+		t.Errorf("expected ErrSessionExists, got %v", err)
+	}
+	if err := conversations.Append("second", llm.NewContent("Tschüss").SetRole(llm.RoleUser)); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := conversations.Load("first"); len(llm.Chain(first)) != 3 {
+		t.Errorf("expected forked session to be unchanged, got %v", conversationTexts(first))
+	}
+	if second, _ := conversations.Load("second"); len(llm.Chain(second)) != 4 {
+		t.Errorf("expected appended content in fork, got %v", conversationTexts(second))
+	}
+	if sessionIDs, err := conversations.List(); err != nil || !reflect.DeepEqual(sessionIDs, []string{"first", "second"}) {
+		t.Errorf("unexpected sessions %v: %v", sessionIDs, err)
+	}
+
+	if err := conversations.Delete("first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conversations.Delete("first"); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+	if err := conversations.Append("../escape", user); !errors.Is(err, store.ErrInvalidSessionID) {
+		t.Errorf("expected ErrInvalidSessionID, got %v", err)
+	}
+}
+
+func TestConversationStores(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		conversations, err := store.NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testConversationStore(t, conversations)
+	})
+	t.Run("bolt", func(t *testing.T) {
+		conversations, err := store.OpenBoltStore(filepath.Join(t.TempDir(), "sessions.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testConversationStore(t, conversations)
+	})
+}
+
+func TestChatSessionStore(t *testing.T) {
+	conversations, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := mock.New().Respond("Ahoy!", "Arr!")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(chat.RegisterWithStore(conversations)); err != nil {
+		t.Fatal(err)
+	}
+	// the second call resumes the session's conversation without passing it as predecessor
+	for _, text := range []string{"Hello", "Who are you?"} {
+		input := llm.NewContent(text).SetRole(llm.RoleUser).With(chat.SessionProperty, "pirate")
+		if _, err := kernel.CallWithName(input, "chat", "chatgpt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conversation, err := conversations.Load("pirate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := conversationTexts(conversation)
+	if len(texts) != 5 || llm.Chain(conversation)[0].Role() != llm.RoleSystem || texts[4] != "assistant: Arr!" {
+		t.Fatalf("unexpected stored conversation: %v", texts)
+	}
+	if input := generator.Calls()[1].Input; len(llm.Chain(input)) != 4 {
+		t.Errorf("expected resumed conversation to be sent, got %v", conversationTexts(input))
+	}
+}
+
+func TestChatSessionHistory(t *testing.T) {
+	conversations, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(mock.New().Respond("Ahoy!", "Arr!").RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(chat.RegisterWithStore(conversations)); err != nil {
+		t.Fatal(err)
+	}
+	// history passed by the caller is stored with a new session
+	history := llm.NewContent("Hi").SetRole(llm.RoleAssistant).WithPredecessor(llm.NewContent("Hello").SetRole(llm.RoleUser))
+	input := llm.NewContent("Who are you?").SetRole(llm.RoleUser).With(chat.SessionProperty, "pirate").WithPredecessor(history)
+	if _, err := kernel.CallWithName(input, "chat", "chatgpt"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := conversations.Load("pirate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"user: Hello", "assistant: Hi", "user: Who are you?", "assistant: Ahoy!"}
+	if texts := conversationTexts(stored); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("expected %v, got %v", expected, texts)
+	}
+
+	// the loaded conversation isn't added to the caller's input
+	input = llm.NewContent("Where are we?").SetRole(llm.RoleUser).With(chat.SessionProperty, "pirate")
+	if _, err := kernel.CallWithName(input, "chat", "chatgpt"); err != nil {
+		t.Fatal(err)
+	}
+	if input.Predecessor() != nil {
+		t.Errorf("expected caller's input to be unchanged, got %v", conversationTexts(input))
+	}
+	if stored, _ := conversations.Load("pirate"); len(llm.Chain(stored)) != 6 {
+		t.Errorf("unexpected stored conversation: %v", conversationTexts(stored))
+	}
+}
+
+func TestChatSummary(t *testing.T) {
+	conversations, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := mock.New().
+		When("^Summarize", "Jane likes pirates.").
+		Respond("Ahoy!", "Arr!", "Yo ho!", "Aye!")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(chat.RegisterWith(chat.WithStore(conversations), chat.WithSummary(10, 2, wordTokenizer{}))); err != nil {
+		t.Fatal(err)
+	}
+	var response llm.Content
+	for i, text := range []string{"Hello", "I like pirates", "Tell me more", "Who am I?"} {
+		input := llm.NewContent(text).SetRole(llm.RoleUser)
+		if i == 0 {
+			input.With(chat.SessionProperty, "jane").With("firstName", "Jane")
+		} else {
+			input.WithPredecessor(response)
+		}
+		if response, err = kernel.CallWithName(input, "chat", "chatgpt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the oldest contents have been summarized twice, the summary replaces the former summary
+	var summaries []string
+	for _, call := range generator.Calls() {
+		if strings.HasPrefix(call.Input.String(), "Summarize") {
+			summaries = append(summaries, call.Input.String())
+		}
+	}
+	if len(summaries) != 2 || !strings.Contains(summaries[0], "user: Hello\nassistant: Ahoy!") || !strings.Contains(summaries[1], "summary: Jane likes pirates.\nuser: I like pirates") {
+		t.Fatalf("unexpected summaries: %q", summaries)
+	}
+	contents := llm.Chain(response)
+	if len(contents) != 6 || contents[0].Role() != llm.RoleSystem || contents[1].Name() != chat.SummaryName || contents[2].String() != "Tell me more" {
+		t.Fatalf("unexpected conversation: %v", conversationTexts(response))
+	}
+	if firstName := response.Property("firstName").String(); firstName != "Jane" {
+		t.Errorf("expected summarized property `firstName` to be kept, got `%s`", firstName)
+	}
+	stored, err := conversations.Load("jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texts := conversationTexts(stored); !reflect.DeepEqual(texts, conversationTexts(response)) {
+		t.Errorf("expected stored conversation to be summarized, got %v", texts)
+	}
+}
+
+func TestChatSummaryStream(t *testing.T) {
+	generator := mock.New().
+		When("^Summarize", "SUMMARY TEXT").
+		Respond("Ahoy!", "Arr!", "final answer")
+	kernel := gosk.NewKernel()
+	kernel.RegisterGenerators(generator.RegisterAs("gpt"))
+	if err := kernel.RegisterSkills(chat.RegisterWith(chat.WithSummary(10, 2, wordTokenizer{}))); err != nil {
+		t.Fatal(err)
+	}
+	function, err := kernel.FindFunction("chat", "chatgpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := llm.ContextWithFunctions(context.Background(), []llm.FunctionDefinition{{Name: "weather"}}, nil)
+	var response llm.Content
+	var received string
+	for i, text := range []string{"Hello", "I like pirates", "Tell me more"} {
+		input := llm.NewContent(text).SetRole(llm.RoleUser)
+		if i > 0 {
+			input.WithPredecessor(response)
+		}
+		deltas := make(chan llm.Content)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			response, err = kernel.CallStream(ctx, input, deltas, function)
+		}()
+		received = ""
+		for delta := range deltas {
+			received += delta.String()
+		}
+		<-done
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the summary is neither streamed nor does its prompt advertise the chat's functions
+	if received != "final answer" {
+		t.Errorf("expected only the final answer to be streamed, got %q", received)
+	}
+	summarized := false
+	for _, call := range generator.Calls() {
+		if strings.HasPrefix(call.Prompt, "Summarize") {
+			summarized = true
+			if len(call.Functions) > 0 {
+				t.Errorf("expected summary without functions, got %v", call.Functions)
+			}
+		}
+	}
+	if !summarized {
+		t.Fatal("expected conversation to be summarized")
+	}
+}
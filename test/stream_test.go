@@ -0,0 +1,70 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+// wordStreamer is a streaming generator that streams its input back word by word
+type wordStreamer struct{}
+
+func (ws wordStreamer) Generate(input llm.Content) (llm.Content, error) {
+	return llm.NewContent(input.String()).SetRole(llm.RoleAssistant), nil
+}
+
+func (ws wordStreamer) GenerateStream(ctx context.Context, input llm.Content, deltas chan<- llm.Content) (llm.Content, error) {
+	for _, word := range strings.SplitAfter(input.String(), " ") {
+		if err := llm.SendDelta(ctx, deltas, llm.NewContent(word)); err != nil {
+			return nil, err
+		}
+	}
+	return ws.Generate(input)
+}
+
+func TestCallStream(t *testing.T) {
+	kernel := gosk.NewKernel()
+	generate := func(ctx context.Context, input llm.Content) (llm.Content, error) {
+		return llm.GenerateContext(ctx, wordStreamer{}, input)
+	}
+	upper := &gosk.Function{Name: "upper", CallContext: func(ctx context.Context, input llm.Content) (llm.Content, error) {
+		return llm.NewContent(strings.ToUpper(input.String())), nil
+	}}
+	echo := &gosk.Function{Name: "echo", CallContext: generate}
+
+	deltas := make(chan llm.Content)
+	var response llm.Content
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		response, err = kernel.CallStream(context.Background(), llm.NewContent("hello streaming world"), deltas, echo, upper, echo)
+	}()
+	received := []string{}
+	for delta := range deltas {
+		received = append(received, delta.String())
+	}
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 3 {
+		t.Fatalf("expected 3 deltas of the last function, got %d: %v", len(received), received)
+	}
+	if strings.Join(received, "") != response.String() || response.String() != "HELLO STREAMING WORLD" {
+		t.Fatalf("unexpected response %q for deltas %v", response, received)
+	}
+
+	// functions without streaming generator send their complete response
+	deltas = make(chan llm.Content, 1)
+	response, err = kernel.CallStream(context.Background(), llm.NewContent("hello"), deltas, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta := <-deltas; delta.String() != "HELLO" {
+		t.Fatalf("unexpected delta %q", delta)
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	llm.RegisterTemplateFuncs(template.FuncMap{
+		"shout": func(text string) string { return strings.ToUpper(text) + "!" },
+	})
+	text := `{{.Property "tags" | join ", "}}|{{json (.Property "tags")}}|{{.Input | truncate 5 | upper}}|` +
+		`{{.Property "missing" | default "none"}}|{{.Property "date" | formatDate "02.01.2006"}}|{{shout "hi"}}|` +
+		"{{toYaml (.Property \"person\") | indent 2}}"
+	promptTemplate, err := llm.TemplateFromText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := llm.NewContent("gophers are great").
+		With("tags", []string{"go", "ai"}).
+		With("date", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)).
+		With("person", map[string]interface{}{"name": "Ida"})
+	prompt, err := llm.ExecuteTemplate(promptTemplate, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `go, ai|["go","ai"]|GOPHE|none|01.07.2023|HI!|  name: Ida`
+	if prompt != expected {
+		t.Fatalf("unexpected prompt:\n%s\nexpected:\n%s", prompt, expected)
+	}
+}
+
+func TestKernelTemplateFuncs(t *testing.T) {
+	generator := mock.New().Echo()
+	fsys := fstest.MapFS{
+		"config.json":   {Data: []byte(`{"name": "catalogue", "description": "List skills", "generator": "default"}`)},
+		"skprompt.tmpl": {Data: []byte(`{{range skills}}{{.Name}};{{end}}{{range $path, $function := functions}}{{$path}}: {{$function.Description}};{{end}}`)},
+	}
+	function, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": generator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernel := gosk.NewKernel()
+	if err = kernel.AddSkills(&gosk.Skill{Name: "meta", Functions: map[string]*gosk.Function{"catalogue": function}}); err != nil {
+		t.Fatal(err)
+	}
+	response, err := kernel.CallContext(context.Background(), llm.NewContent(), function)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "meta;meta.catalogue: List skills;" {
+		t.Fatalf("unexpected response: %s", response)
+	}
+
+	// without kernel the kernel dependent template functions fail
+	if _, err = function.Call(llm.NewContent()); err == nil {
+		t.Fatal("expected error without kernel")
+	}
+}
+
+func TestTemplateCall(t *testing.T) {
+	generator := mock.New().Echo()
+	generators := map[string]llm.Generator{"default": generator}
+	parse := func(name string, prompt string) *gosk.Function {
+		fsys := fstest.MapFS{
+			"config.json":   {Data: []byte(`{"name": "` + name + `", "description": "` + name + `", "generator": "default"}`)},
+			"skprompt.tmpl": {Data: []byte(prompt)},
+		}
+		function, err := gosk.ParseSemanticFunctionFromFS(fsys, generators)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return function
+	}
+	today := &gosk.Function{
+		Name: "today",
+		Call: func(input llm.Content) (llm.Content, error) {
+			return llm.NewContent("Monday"), nil
+		},
+	}
+	kernel := gosk.NewKernel()
+	err := kernel.AddSkills(
+		&gosk.Skill{Name: "time", Functions: map[string]*gosk.Function{"today": today}},
+		&gosk.Skill{Name: "writer", Functions: map[string]*gosk.Function{
+			"translate": parse("translate", `{{.}} in {{.Property "language"}}`),
+			"letter":    parse("letter", `Today is {{call "time.today"}}. {{call "writer.translate" (.Property "greeting")}}`),
+			"loop":      parse("loop", `{{call "writer.loop"}}`),
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := kernel.CallWithName(llm.NewContent().With("greeting", "Hello").With("language", "German"), "writer", "letter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.String() != "Today is Monday. Hello in German" {
+		t.Fatalf("unexpected response: %s", response)
+	}
+
+	_, err = kernel.CallWithName(llm.NewContent(), "writer", "loop")
+	if !errors.Is(err, gosk.ErrRecursiveCall) {
+		t.Fatalf("expected recursive call error, got: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/tokenizer"
+)
+
+// bpeRanks returns tiktoken ranks with all single bytes and given merged tokens
+func bpeRanks(tokens ...string) string {
+	var ranks strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, token := range tokens {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return ranks.String()
+}
+
+func TestBPETokenizer(t *testing.T) {
+	bpe, err := tokenizer.ReadBPE(strings.NewReader(bpeRanks("ll", "he", " w", "hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text   string
+		tokens []int
+	}{
+		// lowest ranks are merged first
+		{"hell", []int{257, 256}},
+		{"hello", []int{259}},
+		// whitespace before text leaves its last space to the text
+		{"a  w", []int{'a', ' ', 258}},
+		{"hello\n\nwow", []int{259, '\n', '\n', 'w', 'o', 'w'}},
+	}
+	for _, test := range tests {
+		if tokens := bpe.Encode(test.text); !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("%q: expected %v, got %v", test.text, test.tokens, tokens)
+		}
+	}
+	if _, err := tokenizer.ReadBPE(strings.NewReader("aGk= 0\n")); err == nil {
+		t.Error("expected error for ranks without all single bytes")
+	}
+}
+
+// wordTokenizer counts words as tokens
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestContextWindowTruncation(t *testing.T) {
+	var conversation llm.Content
+	for i, text := range []string{"system one", "user two", "assistant three", "system four", "user five", "assistant six", "user seven"} {
+		role, _, _ := strings.Cut(text, " ")
+		content := llm.NewContent(text).SetRole(llm.ContentRole(role))
+		if i > 0 {
+			content.WithPredecessor(conversation)
+		}
+		conversation = content
+	}
+	texts := func(content llm.Content) (texts []string) {
+		for _, content := range llm.Chain(content) {
+			texts = append(texts, content.String())
+		}
+		return
+	}
+	tests := []struct {
+		window   llm.ContextWindow
+		expected []string
+	}{
+		{llm.ContextWindow{MaxTokens: 100}, texts(conversation)},
+		{llm.ContextWindow{MaxTokens: 8}, []string{"system one", "system four", "assistant six", "user seven"}},
+		{llm.ContextWindow{MaxTokens: 8, Strategy: llm.TruncateKeepFirstSystem}, []string{"system one", "user five", "assistant six", "user seven"}},
+		{llm.ContextWindow{Strategy: llm.TruncateKeepLast, KeepLast: 2}, []string{"system one", "assistant six", "user seven"}},
+		{llm.ContextWindow{MaxTokens: 9, TokensPerContent: 1, Strategy: llm.TruncateKeepLast, KeepLast: 3}, []string{"system one", "assistant six", "user seven"}},
+		{llm.ContextWindow{MaxTokens: 2, Strategy: llm.TruncateNone}, texts(conversation)},
+	}
+	for i, test := range tests {
+		test.window.Tokenizer = wordTokenizer{}
+		truncated, err := test.window.Truncate(conversation)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(texts(truncated), test.expected) {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, texts(truncated))
+		}
+	}
+	if len(llm.Chain(conversation)) != 7 {
+		t.Error("expected conversation to be unchanged")
+	}
+
+	window := llm.ContextWindow{Tokenizer: wordTokenizer{}, MaxTokens: 5}
+	if _, err := window.Truncate(conversation); !errors.Is(err, llm.ErrContextWindowExceeded) {
+		t.Errorf("expected ErrContextWindowExceeded, got %v", err)
+	}
+}
+
+func TestContextWindowFunctionCalls(t *testing.T) {
+	var conversation llm.Content
+	for i, text := range []string{"system one", "user two", "funcCall three", "funcResponse four", "assistant five", "user six"} {
+		role, _, _ := strings.Cut(text, " ")
+		content := llm.NewContent(text).SetRole(llm.ContentRole(role))
+		if i > 0 {
+			content.WithPredecessor(conversation)
+		}
+		conversation = content
+	}
+	tests := []struct {
+		window   llm.ContextWindow
+		expected []string
+	}{
+		// the function call at the boundary is dropped with its response
+		{llm.ContextWindow{MaxTokens: 8}, []string{"system one", "assistant five", "user six"}},
+		// the kept function response keeps its call
+		{llm.ContextWindow{Strategy: llm.TruncateKeepLast, KeepLast: 3}, []string{"system one", "funcCall three", "funcResponse four", "assistant five", "user six"}},
+	}
+	for i, test := range tests {
+		test.window.Tokenizer = wordTokenizer{}
+		truncated, err := test.window.Truncate(conversation)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		var texts []string
+		for _, content := range llm.Chain(truncated) {
+			texts = append(texts, content.String())
+		}
+		if !reflect.DeepEqual(texts, test.expected) {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, texts)
+		}
+	}
+}
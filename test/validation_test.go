@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestInputValidation(t *testing.T) {
+	minimum, maximum, maxLength := 1.0, 10.0, 5
+	var received llm.Content
+	function := &gosk.Function{
+		Name: "order",
+		InputProperties: map[string]*gosk.Parameter{
+			"":         {Type: gosk.TypeString, Required: true, MaxLength: &maxLength},
+			"quantity": {Type: gosk.TypeInteger, Minimum: &minimum, Maximum: &maximum, Required: true},
+			"size":     {Type: gosk.TypeString, Enum: []string{"S", "M", "L"}, Default: "M"},
+			"express":  {Type: gosk.TypeBoolean},
+			"code":     {Type: gosk.TypeString, Pattern: `^[A-Z]{3}$`},
+			"tags":     {Type: gosk.TypeArray, Items: &gosk.Parameter{Type: gosk.TypeInteger}},
+		},
+		Call: func(input llm.Content) (llm.Content, error) {
+			received = input
+			return llm.NewContent("ok"), nil
+		},
+	}
+	kernel := gosk.NewKernel()
+	if err := kernel.AddSkills(&gosk.Skill{Name: "shop", Functions: map[string]*gosk.Function{"order": function}}); err != nil {
+		t.Fatal(err)
+	}
+
+	input := llm.NewContent("pizza").With("quantity", "3").With("express", "true").With("code", "ABC").With("tags", []interface{}{1.0, "2"})
+	if _, err := kernel.CallContext(context.Background(), input, function); err != nil {
+		t.Fatal(err)
+	}
+	if quantity := received.Property("quantity").Value(); quantity != int64(3) {
+		t.Errorf("quantity not coerced: %#v", quantity)
+	}
+	if express := received.Property("express").Value(); express != true {
+		t.Errorf("express not coerced: %#v", express)
+	}
+	if size := received.Property("size").Value(); size != "M" {
+		t.Errorf("size default not set: %#v", size)
+	}
+	if tags, _ := received.Property("tags").Value().([]interface{}); len(tags) != 2 || tags[0] != int64(1) || tags[1] != int64(2) {
+		t.Errorf("tags not coerced: %#v", received.Property("tags").Value())
+	}
+
+	input = llm.NewContent("a very large pizza").With("size", "XL").With("code", "abc").With("tags", []interface{}{"x"}).With("express", 1)
+	_, err := kernel.CallContext(context.Background(), input, function)
+	var validationErr *gosk.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got: %v", err)
+	}
+	if !errors.Is(err, gosk.ErrMissingParameter) || !errors.Is(err, gosk.ErrInvalidParameter) {
+		t.Errorf("expected missing and invalid parameter errors: %v", err)
+	}
+	offending := map[string]bool{}
+	for _, parameterErr := range validationErr.Errors {
+		if parameterErr.Function != "order" {
+			t.Errorf("unexpected function: %s", parameterErr.Function)
+		}
+		offending[parameterErr.Parameter] = true
+	}
+	for _, name := range []string{"", "quantity", "size", "code", "tags", "express"} {
+		if !offending[name] {
+			t.Errorf("parameter `%s` not reported: %v", name, err)
+		}
+	}
+	t.Log(err)
+}
+
+func TestValidationLimits(t *testing.T) {
+	function := &gosk.Function{Name: "count", InputProperties: map[string]*gosk.Parameter{
+		"count": {Type: gosk.TypeInteger},
+	}}
+	// numbers beyond int64 aren't integers
+	for _, count := range []interface{}{1e19, -1e19, 9.3e18, 9223372036854775808.0} {
+		if err := function.ValidateInput(llm.NewContent("").With("count", count)); !errors.Is(err, gosk.ErrInvalidParameter) {
+			t.Errorf("%v: expected invalid parameter, got: %v", count, err)
+		}
+	}
+	if err := function.ValidateInput(llm.NewContent("").With("count", 9.2e18)); err != nil {
+		t.Errorf("expected valid integer: %v", err)
+	}
+
+	// invalid patterns are reported when the config is loaded
+	fsys := fstest.MapFS{
+		"config.json":   {Data: []byte(`{"name": "code", "description": "Check a code", "inputProperties": {"": {"type": "string", "pattern": "[A-Z"}}, "generator": "default"}`)},
+		"skprompt.tmpl": {Data: []byte(`{{.}}`)},
+	}
+	if _, err := gosk.ParseSemanticFunctionFromFS(fsys, map[string]llm.Generator{"default": mock.New()}); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected invalid pattern error, got: %v", err)
+	}
+}
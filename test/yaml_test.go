@@ -0,0 +1,98 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfmayer/gosk"
+	"github.com/mfmayer/gosk/pkg/llm"
+	"github.com/mfmayer/gosk/pkg/mock"
+)
+
+func TestSkillDefinitionFormats(t *testing.T) {
+	formats := map[string]fstest.MapFS{
+		"json": {
+			"config.json":            {Data: []byte(`{"name": "fun", "description": "Fun skill", "generators": {"default": {"typeID": "gpt"}}}`)},
+			"joke/config.json":       {Data: []byte(`{"name": "joke", "description": "Tell a joke", "generator": "default", "inputProperties": {"": {"description": "Topic", "type": "string", "required": true}}}`)},
+			"joke/skprompt.tmpl":     {Data: []byte("Tell a joke about {{.}}.\n")},
+			"limerick/config.json":   {Data: []byte(`{"name": "limerick", "description": "Write a limerick", "generator": "default"}`)},
+			"limerick/system.tmpl":   {Data: []byte("You are a poet.")},
+			"limerick/skprompt.tmpl": {Data: []byte("Write a limerick about {{.}}.\n")},
+		},
+		"yaml": {
+			"config.yaml":        {Data: []byte("name: fun\ndescription: Fun skill\ngenerators:\n  default:\n    typeID: gpt\n")},
+			"joke/config.yml":    {Data: []byte("name: joke\ndescription: Tell a joke\ngenerator: default\ninputProperties:\n  \"\":\n    description: Topic\n    type: string\n    required: true\n")},
+			"joke/skprompt.tmpl": {Data: []byte("Tell a joke about {{.}}.\n")},
+			"limerick.prompt":    {Data: []byte("---\nname: limerick\ndescription: Write a limerick\ngenerator: default\nsystem: You are a poet.\n---\nWrite a limerick about {{.}}.\n")},
+		},
+		"skill.yaml": {
+			"skill.yaml": {Data: []byte(`name: fun
+description: Fun skill
+generators:
+  default:
+    typeID: gpt
+functions:
+  joke:
+    name: joke
+    description: Tell a joke
+    generator: default
+    inputProperties:
+      "":
+        description: Topic
+        type: string
+        required: true
+    prompt: |
+      Tell a joke about {{.}}.
+  limerick:
+    name: limerick
+    description: Write a limerick
+    generator: default
+    system: You are a poet.
+    prompt: |
+      Write a limerick about {{.}}.
+`)},
+		},
+	}
+
+	generator := mock.New().Echo()
+	generatorFactories := llm.NewGeneratorFuncMap{}
+	typeID, newGenerator := generator.RegisterAs("gpt")()
+	generatorFactories[typeID] = newGenerator
+
+	var expected *gosk.Skill
+	for format, fsys := range formats {
+		skill, err := gosk.ParseSemanticSkillFromFS(fsys, generatorFactories)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(skill.Functions) != 2 {
+			t.Fatalf("%s: expected 2 functions, got %d", format, len(skill.Functions))
+		}
+		for name, function := range skill.Functions {
+			generator.Reset()
+			generator.Echo()
+			response, err := function.Call(llm.NewContent("cats"))
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			messages := chain(generator.Calls()[0].Input)
+			if name == "limerick" && (len(messages) != 2 || messages[0].String() != "You are a poet.") {
+				t.Errorf("%s: expected system message, got %v", format, messages)
+			}
+			if response.String() != map[string]string{"joke": "Tell a joke about cats.\n", "limerick": "Write a limerick about cats.\n"}[name] {
+				t.Errorf("%s: unexpected response of `%s`: %q", format, name, response)
+			}
+			// compare definitions without calls
+			function.Call, function.CallContext = nil, nil
+		}
+		skill.Generators = nil
+		if expected == nil {
+			expected = skill
+			continue
+		}
+		if !reflect.DeepEqual(skill, expected) {
+			t.Errorf("%s: skill differs: %+v", format, skill)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package gosk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mfmayer/gosk/pkg/llm"
+)
+
+var (
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+// ParameterError describes a missing or invalid parameter of a function's input
+type ParameterError struct {
+	// Function whose input is invalid
+	Function string
+	// Parameter that is missing or invalid
+	Parameter string
+	// Value of the invalid parameter
+	Value interface{}
+	// Reason why the parameter is invalid
+	Reason string
+	// Err is ErrMissingParameter or ErrInvalidParameter
+	Err error
+}
+
+func (e *ParameterError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%v: `%s` (function: %s)", e.Err, e.Parameter, e.Function)
+	}
+	return fmt.Sprintf("%v: `%s` (function: %s): %s", e.Err, e.Parameter, e.Function, e.Reason)
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists all missing and invalid parameters of a function's input
+type ValidationError struct {
+	// Function whose input is invalid
+	Function string
+	// Errors of every offending parameter
+	Errors []*ParameterError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// ValidateInput validates the input against the function's input properties before the function is called. Missing properties
+// are set to their defaults and values are coerced to their parameter's type where this is safe (e.g. "42" to integer 42).
+// A ValidationError listing each offending parameter is returned if the input is invalid.
+func (f *Function) ValidateInput(input llm.Content) error {
+	if validationErr := validateProperties(f.Name, f.InputProperties, input); validationErr != nil {
+		return validationErr
+	}
+	return nil
+}
+
+// validateProperties validates the content's properties against given parameters, sets defaults and coerces values
+func validateProperties(functionName string, parameters map[string]*Parameter, content llm.Content) *ValidationError {
+	validationErr := &ValidationError{Function: functionName}
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parameter := parameters[name]
+		value := content.Property(name).Value()
+		if value == nil && parameter.Default != nil {
+			content.With(name, parameter.Default)
+			value = content.Property(name).Value()
+		}
+		if value == nil {
+			if parameter.Required {
+				validationErr.Errors = append(validationErr.Errors, &ParameterError{Function: functionName, Parameter: name, Err: ErrMissingParameter})
+			}
+			continue
+		}
+		coerced, reasons := parameter.validate(value)
+		for _, reason := range reasons {
+			validationErr.Errors = append(validationErr.Errors, &ParameterError{Function: functionName, Parameter: name, Value: value, Reason: reason, Err: ErrInvalidParameter})
+		}
+		if len(reasons) <= 0 && !reflect.DeepEqual(coerced, value) {
+			content.With(name, coerced)
+		}
+	}
+	if len(validationErr.Errors) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+// Validate validates the value against the parameter's type and constraints and returns the value coerced to the parameter's type.
+// Reasons are returned for every violation.
+func (p *Parameter) Validate(value interface{}) (coerced interface{}, err error) {
+	coerced, reasons := p.validate(value)
+	if len(reasons) > 0 {
+		err = fmt.Errorf("%w: %s", ErrInvalidParameter, strings.Join(reasons, ", "))
+	}
+	return
+}
+
+func (p *Parameter) validate(value interface{}) (coerced interface{}, reasons []string) {
+	coerced, ok := coerce(value, p.Type)
+	if !ok {
+		return value, []string{fmt.Sprintf("expected %s, got %s", p.Type, describeType(value))}
+	}
+	if len(p.Enum) > 0 {
+		found := false
+		for _, enum := range p.Enum {
+			if fmt.Sprint(coerced) == enum {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reasons = append(reasons, fmt.Sprintf("value `%v` is not one of [%s]", coerced, strings.Join(p.Enum, ", ")))
+		}
+	}
+	if number, ok := toFloat(coerced); ok {
+		if p.Minimum != nil && number < *p.Minimum {
+			reasons = append(reasons, fmt.Sprintf("value %v is less than minimum %v", coerced, *p.Minimum))
+		}
+		if p.Maximum != nil && number > *p.Maximum {
+			reasons = append(reasons, fmt.Sprintf("value %v is greater than maximum %v", coerced, *p.Maximum))
+		}
+	}
+	if text, ok := coerced.(string); ok {
+		length := utf8.RuneCountInString(text)
+		if p.MinLength != nil && length < *p.MinLength {
+			reasons = append(reasons, fmt.Sprintf("length %d is less than minimum length %d", length, *p.MinLength))
+		}
+		if p.MaxLength != nil && length > *p.MaxLength {
+			reasons = append(reasons, fmt.Sprintf("length %d is greater than maximum length %d", length, *p.MaxLength))
+		}
+		if p.Pattern != "" {
+			pattern := p.pattern
+			var err error
+			if pattern == nil || pattern.String() != p.Pattern {
+				// the pattern hasn't been compiled with the config or has been changed since
+				pattern, err = regexp.Compile(p.Pattern)
+			}
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("invalid pattern `%s`: %v", p.Pattern, err))
+			} else if !pattern.MatchString(text) {
+				reasons = append(reasons, fmt.Sprintf("value `%s` doesn't match pattern `%s`", text, p.Pattern))
+			}
+		}
+	}
+	if items, ok := coerced.([]interface{}); ok && p.Items != nil {
+		coercedItems := make([]interface{}, len(items))
+		for i, item := range items {
+			coercedItem, itemReasons := p.Items.validate(item)
+			for _, reason := range itemReasons {
+				reasons = append(reasons, fmt.Sprintf("item %d: %s", i, reason))
+			}
+			coercedItems[i] = coercedItem
+		}
+		coerced = coercedItems
+	}
+	return
+}
+
+// coerce converts the value to given type if this is safe and returns false if it isn't
+func coerce(value interface{}, parameterType Type) (interface{}, bool) {
+	switch parameterType {
+	case "":
+		return value, true
+	case TypeNull:
+		return value, value == nil
+	case TypeString:
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+		if _, ok := toFloat(value); ok {
+			return fmt.Sprint(value), true
+		}
+	case TypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, true
+			}
+		}
+	case TypeInteger:
+		if text, ok := value.(string); ok {
+			i, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+			return i, err == nil
+		}
+		// float64(1<<63) is the first number that overflows int64
+		if number, ok := toFloat(value); ok && number >= -(1<<63) && number < 1<<63 && number == float64(int64(number)) {
+			if _, isFloat := value.(float64); isFloat {
+				return int64(number), true
+			}
+			return value, true
+		}
+	case TypeNumber:
+		if text, ok := value.(string); ok {
+			number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			return number, err == nil
+		}
+		if _, ok := toFloat(value); ok {
+			return value, true
+		}
+	case TypeArray:
+		if text, ok := value.(string); ok {
+			var items []interface{}
+			err := json.Unmarshal([]byte(text), &items)
+			return items, err == nil
+		}
+		if items, ok := value.([]interface{}); ok {
+			return items, true
+		}
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			items := make([]interface{}, v.Len())
+			for i := range items {
+				items[i] = v.Index(i).Interface()
+			}
+			return items, true
+		}
+	case TypeObject:
+		if _, ok := value.(map[string]interface{}); ok {
+			return value, true
+		}
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Map || v.Kind() == reflect.Struct {
+			return value, true
+		}
+	}
+	return value, false
+}
+
+// toFloat converts numeric values to float64
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// describeType describes the value's type for error messages
+func describeType(value interface{}) string {
+	if value == nil {
+		return string(TypeNull)
+	}
+	if text, ok := value.(string); ok {
+		return fmt.Sprintf("string `%s`", text)
+	}
+	return fmt.Sprintf("%T", value)
+}